@@ -0,0 +1,121 @@
+package main
+
+// Converts graph files between formats, mostly to make the binary graphs the server loads at startup
+// The input format is detected, the output format comes from the -out extension: .bin (binary) or .json
+// Use -all to write a binary copy next to every graph in a directory, that's what the Docker build runs
+// -embed writes the gzipped graphs a -tags embedgraph build of cmd/search carries inside the binary
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+func main() {
+	in := flag.String("in", graph.FileName(graph.DefaultLang, ""), "graph file to convert (any format)")
+	out := flag.String("out", "", "output file, the extension picks the format (defaults to -in with a .bin extension)")
+	all := flag.Bool("all", false, "convert every graph*.json and graph*.jsonl in -dir to a .bin next to it")
+	dir := flag.String("dir", ".", "directory -all and -embed look in")
+	embed := flag.String("embed", "", "write gzipped binary copies of the graphs in -dir plus their nodes, contexts and relations files here (cmd/search/embedded for a -tags embedgraph build)")
+	flag.Parse()
+
+	if *embed != "" {
+		if err := writeEmbedded(*dir, *embed); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *all {
+		if err := convertAll(*dir); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(*in, filepath.Ext(*in)) + ".bin"
+	}
+	if err := convert(*in, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// convertAll writes the binary copy of every JSON and compact graph in dir
+func convertAll(dir string) error {
+	var paths []string
+	for _, pattern := range []string{"graph*.json", "graph*.jsonl"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no graph files in %q", dir)
+	}
+	for _, path := range paths {
+		if err := convert(path, strings.TrimSuffix(path, filepath.Ext(path))+".bin"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEmbedded writes the graphs to build into the server, then loads them back the way the server will so a
+// build never embeds graphs it can't start with
+func writeEmbedded(dir, out string) error {
+	if err := graph.WriteEmbedded(dir, out); err != nil {
+		return err
+	}
+	store, err := graph.LoadStoreFS(os.DirFS(out), graph.Lenient)
+	if err != nil {
+		return fmt.Errorf("wrote %s but it doesn't load back: %w", out, err)
+	}
+	log.Printf("Embedded graphs %v in %s", store.Names(), out)
+	return nil
+}
+
+// convert reads a graph in any format and writes it in the one the output's extension asks for
+func convert(in, out string) error {
+	start := time.Now()
+	file, people, err := graph.LoadAnyGraph(in)
+	if err != nil {
+		return err
+	}
+	loaded := time.Since(start)
+
+	switch filepath.Ext(out) {
+	case ".bin":
+		err = graph.WriteBinaryGraph(out, file, people)
+	case ".json":
+		if people != nil {
+			return fmt.Errorf("%s is a full article graph, a .json file can't mark its people (use .bin)", in)
+		}
+		err = graph.WriteGraphFile(out, file)
+	default:
+		return fmt.Errorf("don't know which format %q is, use .bin or .json", out)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+
+	// Loading the result back the way the server does checks it and shows what the server will save at startup
+	start = time.Now()
+	if _, err := graph.LoadDataset(out, graph.Lenient); err != nil {
+		return fmt.Errorf("wrote %s but it doesn't load back: %w", out, err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s -> %s: %d nodes, %d edges, %d KB (load %s -> %s)", in, out, len(file.Graph), graph.CountEdges(file.Graph),
+		info.Size()/1024, loaded.Round(time.Millisecond), time.Since(start).Round(time.Millisecond))
+	return nil
+}
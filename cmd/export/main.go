@@ -0,0 +1,52 @@
+package main
+
+// Exports a graph for other tools: GraphML and GEXF (Gephi, Cytoscape, networkx), DOT (Graphviz) and CSV
+// Nodes carry their label, in and out degree, community (label propagation), tags and description
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+func main() {
+	in := flag.String("in", graph.FileName(graph.DefaultLang, ""), "graph file to export (any format), its nodes.json is read for descriptions")
+	format := flag.String("format", graph.FormatGraphML, "graphml, gexf, dot, or csv for both nodes-csv and edges-csv")
+	out := flag.String("out", "", "output file (defaults to -in with the format's extension, csv writes <out>.nodes.csv and <out>.edges.csv)")
+	flag.Parse()
+
+	formats := []string{*format}
+	if *format == "csv" {
+		formats = []string{graph.FormatNodesCSV, graph.FormatEdgesCSV}
+	}
+	for _, f := range formats {
+		if !slices.Contains(graph.ExportFormats, f) {
+			log.Fatalf("unknown format %q, use graphml, gexf, dot or csv", f)
+		}
+	}
+
+	ds, err := graph.LoadDataset(*in, graph.Lenient)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	base := *out
+	if base == "" {
+		base = strings.TrimSuffix(*in, filepath.Ext(*in))
+	}
+	for _, f := range formats {
+		path := base
+		if *out == "" || len(formats) > 1 {
+			path = strings.TrimSuffix(base, filepath.Ext(base)) + graph.ExportExt(f)
+		}
+		if err := graph.WriteExport(path, ds, f); err != nil {
+			log.Fatalf("failed to write %s: %v", path, err)
+		}
+		fmt.Printf("Wrote %s (%d nodes, %d edges)\n", path, ds.Graph.Len(), ds.Graph.NumEdges())
+	}
+}
@@ -1,14 +1,243 @@
 package main
 
 // Data collection: Creates graph.json so you can run main file within search subdirectory
+// Use -lang to build another language edition's graph (graph.<lang>.json), e.g. -lang de
+// Use -prose to also build the prose-only variant (graph.<lang>.prose.json)
+// Use -full-depth to build the full article graph instead (graph.<lang>.full.jsonl)
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
 	"github.com/Rani-Codes/sixth_degree/internal/fetcher"
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
 )
 
 func main() {
-	validNames := fetcher.LoadValidNames("seed_names.txt")
-	pool := fetcher.NewWorkerPool(10, validNames)
-	pool.Run("seed_names.txt")
+	lang := flag.String("lang", graph.DefaultLang, "Wikipedia language edition to fetch from (en, de, fr, ja, ...)")
+	seedFile := flag.String("seeds", "seed_names.txt", "seed list of (English) article titles")
+	workers := flag.Int("workers", 10, "number of concurrent fetch workers")
+	out := flag.String("out", "", "output file (defaults to graph.json or graph.<lang>.json)")
+	retries := flag.Int("retries", 1, "extra attempts for a page that failed to fetch")
+	retryDelay := flag.Duration("retry-delay", 30*time.Second, "wait before retrying a failed page, other pages keep fetching meanwhile")
+	failuresFile := flag.String("failures", "failures.json", "where to write pages that still failed (empty to skip)")
+	reportFile := flag.String("report", "fetch_report.json", "where to write the final summary report (empty to skip)")
+	nodes := flag.String("nodes", "", "per person metadata file (defaults to nodes.json or nodes.<lang>.json, \"-\" to skip)")
+	prose := flag.Bool("prose", false, "also build a prose-only graph (graph.<lang>.prose.json) without infobox and navbox/template links")
+	withContext := flag.Bool("context", false, "also parse each article's wikitext to record where every link sits (contexts.json)")
+	wikidata := flag.Bool("wikidata", false, "also build typed edges (spouse, child, employer, ...) between seeds from Wikidata (relations.json)")
+	wikidataEndpoint := flag.String("wikidata-endpoint", "", "wbgetentities endpoint, defaults to www.wikidata.org (point it at a local fixture server for testing)")
+	expandRounds := flag.Int("expand", 0, "expansion rounds: add the most linked pages that are people (Wikidata human or biography categories) as seeds and fetch them")
+	expandTop := flag.Int("expand-top", 100, "people added per expansion round")
+	maxNodes := flag.Int("max-nodes", 20000, "stop expanding once there are this many seeds (0 for no cap)")
+	expansionFile := flag.String("expansion", "expansion.json", "where to write who expansion added and why")
+	fullDepth := flag.Int("full-depth", 0, "build the full article graph (graph.<lang>.full.jsonl) instead: links to any article up to this many links from a seed")
+	fullMaxPages := flag.Int("full-max-pages", 2000000, "stop adding pages to the full article graph at this many (0 for no cap)")
+	cacheDir := flag.String("cache", "", "keep API responses in this directory and reuse them on the next run (e.g. .cache)")
+	cacheTTL := flag.Duration("cache-ttl", 24*time.Hour, "serve cached responses younger than this without asking, older ones get revalidated")
+	offline := flag.Bool("offline", false, "only serve responses from -cache, never hit the network")
+	shard := flag.Int("shard", 0, "which shard of the seed list to fetch, from 0 to -shards minus 1")
+	shards := flag.Int("shards", 1, "split the seed list into this many shards fetched separately and combined with cmd/merge")
+	incremental := flag.Bool("incremental", false, "only refetch pages whose revision changed since the existing output file was built")
+	maxFailureRate := flag.Float64("max-failure-rate", 0.01, "exit non-zero if more than this fraction of pages failed")
+	flag.Parse()
+
+	if *shards > 1 {
+		shardFlags(*shard, *shards, *lang, out, nodes, failuresFile, reportFile)
+		if *withContext || *prose || *wikidata || *expandRounds > 0 || *incremental || *fullDepth > 0 {
+			log.Fatal("-shards only builds the link graph, -context, -prose, -wikidata, -expand, -incremental and -full-depth need a single fetch")
+		}
+	}
+	if *out == "" {
+		*out = graph.FileName(*lang, "")
+	}
+	if *nodes == "" {
+		*nodes = graph.NodesFileName(*lang)
+	} else if *nodes == "-" {
+		*nodes = ""
+	}
+
+	// Ctrl-C stops the fetch without writing anything, responses fetched so far stay in the -cache
+	ctx, stop := fetcher.SignalContext()
+	defer stop()
+
+	source := fetcher.NewLinkSource(*lang)
+	source.Cache = openCache(*cacheDir, *cacheTTL, *offline)
+	if *fullDepth > 0 {
+		runFull(ctx, source, *seedFile, *workers, *fullDepth, *fullMaxPages, *maxFailureRate)
+		return
+	}
+
+	validNames, err := fetcher.LoadValidNames(*seedFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool := fetcher.NewWorkerPool(*workers, validNames, source, *out)
+	pool.Retries = *retries
+	pool.RetryDelay = *retryDelay
+	pool.FailuresFile = *failuresFile
+	pool.ReportFile = *reportFile
+	pool.NodesFile = *nodes
+	pool.Shard = *shard
+	pool.Shards = *shards
+	if *withContext {
+		pool.ContextsFile = graph.ContextsFileName(*lang)
+	}
+	if *prose {
+		pool.ProseFile = graph.FileName(*lang, graph.VariantProse)
+	}
+	if *wikidata {
+		pool.RelationsFile = graph.RelationsFileName(*lang)
+		pool.Wikidata = fetcher.NewWikidataSource(*wikidataEndpoint)
+		pool.Wikidata.Cache = source.Cache
+	}
+
+	if *expandRounds > 0 {
+		// Expansion finds candidates among English article titles and a reused page has no links to rank
+		if *lang != graph.DefaultLang {
+			log.Fatalf("-expand only works on the %s graph, expand there and build other languages from its seed list", graph.DefaultLang)
+		}
+		if *incremental {
+			log.Fatal("-expand can't be combined with -incremental")
+		}
+		pool.ExpandRounds = *expandRounds
+		pool.ExpandTop = *expandTop
+		pool.MaxNodes = *maxNodes
+		pool.ExpansionFile = *expansionFile
+		if pool.Wikidata == nil {
+			pool.Wikidata = fetcher.NewWikidataSource(*wikidataEndpoint)
+			pool.Wikidata.Cache = source.Cache
+		}
+	}
+
+	if *incremental {
+		usePrevious(pool, *out, *lang)
+	}
+
+	// Seeds are English titles, so other editions need the langlinks mapping first
+	if source.Lang != graph.DefaultLang {
+		seeds := make([]string, 0, len(validNames))
+		for name := range validNames {
+			seeds = append(seeds, name)
+		}
+		en := fetcher.NewLinkSource(graph.DefaultLang)
+		en.Cache = source.Cache
+		titles, err := en.TranslateTitles(ctx, seeds, source.Lang)
+		if err != nil {
+			exitIfInterrupted(ctx, source.Cache)
+			log.Fatalf("failed to map seeds to %s: %v", source.Host(), err)
+		}
+		log.Printf("%d of %d seeds have a %s article", len(titles), len(validNames), source.Host())
+		pool.SetTitles(titles)
+	}
+
+	report, err := pool.Run(ctx, *seedFile)
+	if err != nil {
+		exitIfInterrupted(ctx, source.Cache)
+		log.Fatal(err)
+	}
+	if source.Cache != nil {
+		log.Print(source.Cache.Summary())
+	}
+	log.Printf("Fetched %d/%d pages, %d failed (%.2f%%)", report.Fetched, report.Total, report.Failed, 100*report.FailureRate())
+
+	// Too many failures means the graph is missing a chunk of edges, fail the build instead of shipping it
+	if report.FailureRate() > *maxFailureRate {
+		log.Printf("Failure rate above -max-failure-rate %.2f%%", 100**maxFailureRate)
+		os.Exit(1)
+	}
+}
+
+// exitIfInterrupted exits when the error came from a Ctrl-C, with the shell's usual 130 for an interrupted command
+func exitIfInterrupted(ctx context.Context, cache *fetcher.ResponseCache) {
+	if ctx.Err() == nil {
+		return
+	}
+	log.Print("Interrupted, no output files were written")
+	if cache != nil {
+		log.Printf("%s, a rerun picks up from there", cache.Summary())
+	}
+	os.Exit(130)
+}
+
+// openCache opens the response cache shared by every source, nil when -cache isn't set
+func openCache(dir string, ttl time.Duration, offline bool) *fetcher.ResponseCache {
+	if dir == "" {
+		if offline {
+			log.Fatal("-offline needs a -cache directory to serve from")
+		}
+		return nil
+	}
+	cache, err := fetcher.NewResponseCache(dir, ttl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cache.Offline = offline
+	return cache
+}
+
+// shardFlags checks the shard flags and points the outputs at per shard files so shards can share a directory
+// Metadata comes from cmd/merge, which fetches it once for the whole merged graph
+func shardFlags(shard, shards int, lang string, out, nodes, failuresFile, reportFile *string) {
+	if shard < 0 || shard >= shards {
+		log.Fatalf("-shard has to be between 0 and %d", shards-1)
+	}
+	suffix := fmt.Sprintf(".%d-of-%d.json", shard, shards)
+	if *out == "" {
+		*out = fetcher.PartialFileName(lang, shard, shards)
+	}
+	*nodes = "-"
+	if *failuresFile == "failures.json" {
+		*failuresFile = "failures" + suffix
+	}
+	if *reportFile == "fetch_report.json" {
+		*reportFile = "fetch_report" + suffix
+	}
+}
+
+// usePrevious hands the existing build to the pool so unchanged pages can be carried over
+func usePrevious(pool *fetcher.WorkerPool, out, lang string) {
+	prev, err := graph.LoadGraphFile(out)
+	if err != nil {
+		log.Printf("No previous build to reuse (%v), doing a full fetch", err)
+		return
+	}
+	// Kept keyed by QID, the pool maps nodes back to seed names once it knows each seed's title
+	pool.Previous = prev
+
+	if pool.ContextsFile != "" || pool.ProseFile != "" {
+		contexts, err := graph.LoadContexts(graph.ContextsFileName(lang))
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatalf("failed to load previous link contexts: %v", err)
+		}
+		pool.PreviousContexts = contexts
+	}
+}
+
+// runFull builds the full article graph, where paths can go through any article and people are the endpoints
+func runFull(ctx context.Context, source *fetcher.LinkSource, seedFile string, workers, depth, maxPages int, maxFailureRate float64) {
+	// Seeds are English titles and the crawl follows links as they are, there's no langlinks mapping for millions of pages
+	if source.Lang != graph.DefaultLang {
+		log.Fatalf("-full-depth only works on the %s graph", graph.DefaultLang)
+	}
+	crawler := fetcher.NewCrawler(workers, source, graph.FileName(source.Lang, graph.VariantFull))
+	crawler.Depth = depth
+	crawler.MaxPages = maxPages
 
+	failed, err := crawler.Run(ctx, seedFile)
+	if err != nil {
+		exitIfInterrupted(ctx, source.Cache)
+		log.Fatal(err)
+	}
+	if source.Cache != nil {
+		log.Print(source.Cache.Summary())
+	}
+	if total := crawler.Pages(); total > 0 && float64(failed)/float64(total) > maxFailureRate {
+		log.Printf("%d of %d pages failed, above -max-failure-rate %.2f%%", failed, total, 100*maxFailureRate)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,108 @@
+package main
+
+// Imports a network from a CSV or TSV edge list or a GraphML file into a graph file the server and every other
+// command read, so the same search and UI work on an org chart or a citation network
+// Put the result in a directory of its own as graph.json, or in snapshots/<name>/ next to the Wikipedia graph
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+func main() {
+	in := flag.String("in", "", "edge list (.csv, .tsv) or GraphML (.graphml) to import")
+	out := flag.String("out", "", "graph file to write, .json or .bin (required, so graph.json never gets overwritten by accident)")
+	format := flag.String("format", "", "csv, tsv or graphml (defaults to the -in extension)")
+	labels := flag.String("labels", "", "node file with id and label (or name) columns and optionally tags, CSV or .tsv (e.g. the nodes file cmd/export writes)")
+	source := flag.String("source", "source", "edge list column holding the source node")
+	target := flag.String("target", "target", "edge list column holding the target node")
+	noHeader := flag.Bool("no-header", false, "the edge list has no header row, the first two columns are source and target")
+	undirected := flag.Bool("undirected", false, "every edge goes both ways")
+	flag.Parse()
+
+	if *in == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*in)), ".")
+	}
+
+	file, err := importFile(*in, *format, *labels, graph.ImportOptions{
+		Source:     *source,
+		Target:     *target,
+		NoHeader:   *noHeader,
+		Undirected: *undirected,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch filepath.Ext(*out) {
+	case ".bin":
+		err = graph.WriteBinaryGraph(*out, file, nil)
+	case ".json":
+		err = graph.WriteGraphFile(*out, file)
+	default:
+		log.Fatalf("don't know which format %q is, use .json or .bin", *out)
+	}
+	if err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+
+	// Loading it back the way the server does checks nothing dangles and nothing was lost
+	if _, err := graph.LoadGraph(*out, graph.Strict); err != nil {
+		log.Fatalf("wrote %s but it doesn't load back: %v", *out, err)
+	}
+	fmt.Printf("Wrote %s: %d nodes, %d edges, %d labels\n", *out, file.Meta.Nodes, file.Meta.Edges, len(file.Labels))
+}
+
+// importFile reads the input in the given format, then the labels file if there is one
+func importFile(in, format, labels string, opts graph.ImportOptions) (*models.GraphFile, error) {
+	f, err := os.Open(in)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var file *models.GraphFile
+	switch format {
+	case "csv":
+		opts.Comma = ','
+		file, err = graph.ImportEdgeList(f, in, opts)
+	case "tsv", "tab":
+		opts.Comma = '\t'
+		file, err = graph.ImportEdgeList(f, in, opts)
+	case "graphml", "xml":
+		file, err = graph.ImportGraphML(f, in, opts.Undirected)
+	default:
+		return nil, fmt.Errorf("don't know how to import %q files, use -format csv, tsv or graphml", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if labels == "" {
+		return file, nil
+	}
+
+	lf, err := os.Open(labels)
+	if err != nil {
+		return nil, err
+	}
+	defer lf.Close()
+	comma := ','
+	if ext := strings.ToLower(filepath.Ext(labels)); ext == ".tsv" || ext == ".tab" {
+		comma = '\t'
+	}
+	if err := graph.ImportLabels(file, lf, labels, comma); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+// Combines the partial graphs of a sharded fetch (cmd/fetcher -shard i -shards n) into the final graph
+// Checks every shard is there exactly once, reports names fetched by more than one shard and names no shard fetched,
+// then fetches the metadata (QIDs) for the whole graph once and writes graph.json and nodes.json
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Rani-Codes/sixth_degree/internal/fetcher"
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+func main() {
+	lang := flag.String("lang", graph.DefaultLang, "language edition the partials were fetched from")
+	out := flag.String("out", "", "merged graph file (defaults to graph.json or graph.<lang>.json)")
+	nodes := flag.String("nodes", "", "per person metadata file (defaults to nodes.json or nodes.<lang>.json, \"-\" to skip)")
+	reportFile := flag.String("report", "", "also write the merge report as JSON to this file")
+	strict := flag.Bool("strict", false, "exit non-zero when names overlap between shards or are missing from all of them")
+	flag.Parse()
+
+	if *out == "" {
+		*out = graph.FileName(*lang, "")
+	}
+	if *nodes == "" {
+		*nodes = graph.NodesFileName(*lang)
+	} else if *nodes == "-" {
+		*nodes = ""
+	}
+
+	// Partial files come as arguments, or every partial.<lang>.*-of-*.json in the current directory
+	files := flag.Args()
+	if len(files) == 0 {
+		var err error
+		files, err = filepath.Glob(fmt.Sprintf("partial.%s.*-of-*.json", *lang))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		log.Fatalf("no partial graphs found, pass them as arguments or run from the directory the shards wrote to")
+	}
+
+	partials := make([]*models.GraphFile, 0, len(files))
+	for _, f := range files {
+		p, err := graph.LoadGraphFile(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		partials = append(partials, p)
+	}
+
+	merged, report, err := graph.MergePartials(partials)
+	if err != nil {
+		log.Fatalf("can't merge %v: %v", files, err)
+	}
+	log.Printf("Merged %d shards into %d nodes", report.Shards, report.Nodes)
+	printNames("Fetched by more than one shard (links combined)", report.Overlap)
+	printNames("Missing from every shard (failed pages)", report.Missing)
+
+	// Shards skip metadata, fetch it once here so the merged graph is keyed by QID like a normal fetch
+	// Seed names are English titles, so the lookup goes through English Wikipedia whatever the graph's language
+	var people map[string]models.Person
+	if *nodes != "" {
+		names := make([]string, 0, len(merged.Graph))
+		for name := range merged.Graph {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		log.Printf("Fetching metadata for %d pages", len(names))
+		ctx, stop := fetcher.SignalContext()
+		people, err = fetcher.NewLinkSource(graph.DefaultLang).FetchMetadata(ctx, names)
+		stop()
+		if err != nil {
+			log.Fatalf("metadata fetch failed: %v", err)
+		}
+		if err := graph.WriteNodes(*nodes, people); err != nil {
+			log.Fatalf("failed to write %s: %v", *nodes, err)
+		}
+		log.Printf("Wrote metadata for %d people to %s", len(people), *nodes)
+	}
+
+	graph.AssignIDs(merged, people)
+	if err := graph.WriteGraphFile(*out, merged); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+	log.Printf("Wrote %d nodes and %d edges to %s", merged.Meta.Nodes, merged.Meta.Edges, *out)
+
+	if *reportFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to marshal merge report: %v", err)
+		}
+		if err := graph.WriteFileAtomic(*reportFile, data); err != nil {
+			log.Fatalf("failed to write %s: %v", *reportFile, err)
+		}
+	}
+
+	if *strict && (len(report.Overlap) > 0 || len(report.Missing) > 0) {
+		os.Exit(1)
+	}
+}
+
+// printNames lists up to 20 names under a heading, nothing when the list is empty
+func printNames(heading string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Printf("%s: %d\n", heading, len(names))
+	for i, name := range names {
+		if i == 20 {
+			fmt.Printf("  ... and %d more\n", len(names)-20)
+			break
+		}
+		fmt.Printf("  %s\n", name)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build embedgraph
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+)
+
+// Built with -tags embedgraph the graphs come inside the binary, from the gzipped files
+// `go run ./cmd/convert -embed cmd/search/embedded` writes. The directory only holds a README on a clean checkout,
+// the server then loads its graphs from disk like a build without the tag.
+//
+//go:embed embedded
+var embedded embed.FS
+
+// embeddedGraphs returns the graphs built into the binary, ok is false for a build without them
+func embeddedGraphs() (files fs.FS, ok bool) {
+	files, err := fs.Sub(embedded, "embedded")
+	if err != nil {
+		panic(err) // Only for an invalid path, "embedded" isn't one
+	}
+	graphs, err := fs.Glob(files, "graph*")
+	if err != nil {
+		panic(err) // Only for a bad pattern
+	}
+	if len(graphs) == 0 {
+		log.Println("Built with -tags embedgraph but no graphs were embedded, loading them from disk")
+		return nil, false
+	}
+	return files, true
+}
@@ -0,0 +1,10 @@
+//go:build !embedgraph
+
+package main
+
+import "io/fs"
+
+// embeddedGraphs returns the graphs built into the binary, a build without -tags embedgraph has none
+func embeddedGraphs() (files fs.FS, ok bool) {
+	return nil, false
+}
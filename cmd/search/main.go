@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/Rani-Codes/sixth_degree/internal/graph"
@@ -15,25 +20,45 @@ import (
 
 // BFS & Websocket server here
 func main() {
-	g, err := graph.LoadGraph("graph.json")
+	strict := flag.Bool("strict", false, "refuse to start when a graph has dangling edges, self-loops, duplicate neighbors, empty names or null neighbor lists (repaired with a warning otherwise)")
+	watch := flag.Duration("watch", 0, "check the graph files this often and reload them when they change (0 turns it off)")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "bearer token for POST /api/admin/reload, the endpoint is off without one (default $ADMIN_TOKEN)")
+	graphDir := flag.String("graphs", os.Getenv("GRAPH_DIR"), "directory to load the graphs from, overrides the graphs built in with -tags embedgraph (default $GRAPH_DIR, then the embedded graphs or the working directory)")
+	flag.Parse()
+
+	mode := graph.Lenient
+	if *strict {
+		mode = graph.Strict
+	}
+
+	// One graph per language edition and variant: graph.json (English) plus any graph.<lang>[.<variant>].json
+	// Reloads swap in a whole new set of graphs, a failed one keeps serving the old set
+	live, err := loadGraphs(*graphDir, mode)
 	if err != nil {
 		log.Fatal(err)
 	}
+	go reloadOnHangup(live)
+	if *watch > 0 {
+		go live.Watch(context.Background(), *watch)
+	}
 
-	log.Printf("Loaded graph with %d nodes\n", len(*g))
-
-	// Initialize handlers with the graph
-	peopleHandler := handlers.NewPeopleHandler(*g)
-	graphHandler := handlers.NewGraphHandler(*g)
+	// Initialize handlers with the graphs
+	peopleHandler := handlers.NewPeopleHandler(live)
+	graphHandler := handlers.NewGraphHandler(live)
+	exportHandler := handlers.NewExportHandler(live)
+	adminHandler := handlers.NewAdminHandler(live, *adminToken)
 
 	// Register WebSocket handler for /ws endpoint
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		handleWebSocket(w, r, *g)
+		handleWebSocket(w, r, live)
 	})
 
 	// Register GET routes
 	http.HandleFunc("/api/people", peopleHandler.HandleGetPeople)
 	http.HandleFunc("/api/graph", graphHandler.HandleGetGraph)
+	http.HandleFunc("/api/graphs", graphHandler.HandleListGraphs)
+	http.HandleFunc("/api/export", exportHandler.HandleExport)
+	http.HandleFunc("/api/admin/reload", adminHandler.HandleReload)
 
 	// Show the built website from ./dist. If we can't find a file, show index.html
 	// Works in Docker and also if you ran `npm run build` locally
@@ -70,13 +95,35 @@ func main() {
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
+// loadGraphs serves the graphs in dir, or when it's empty the ones built into the binary (if any) or the ones
+// in the working directory
+func loadGraphs(dir string, mode graph.ValidationMode) (*graph.Live, error) {
+	if dir != "" {
+		return graph.NewLive(dir, mode)
+	}
+	if files, ok := embeddedGraphs(); ok {
+		return graph.NewLiveFS(files, mode)
+	}
+	return graph.NewLive(".", mode)
+}
+
+// reloadOnHangup reloads the graphs on every SIGHUP (kill -HUP <pid>)
+func reloadOnHangup(live *graph.Live) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		log.Println("Got SIGHUP, reloading graphs")
+		live.Reload() // Logs its own failure
+	}
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allowing all origins for now (change in prod)
 	},
 }
 
-func handleWebSocket(w http.ResponseWriter, r *http.Request, g models.Graph) {
+func handleWebSocket(w http.ResponseWriter, r *http.Request, live *graph.Live) {
 	// Upgrades the HTTP server connection to the WebSocket protocol.
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -102,6 +149,14 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, g models.Graph) {
 			break // If client disconnected or sent invalid JSON -> exit for loop
 		}
 
+		// Pick the graph for the requested snapshot (latest by default), language edition (English by default)
+		// and variant (all links between people by default). The search sticks with this dataset even if a reload swaps in a new one halfway through
+		ds, err := live.Store().Get(request.Graph, request.Lang, request.Variant)
+		if err != nil {
+			conn.WriteJSON(models.WSResponse{Type: "error", Data: err.Error()})
+			continue
+		}
+
 		// Collect per-level nodes and counts; stream one message when a level completes
 		levelCounts := make(map[int]int)
 		nodeLevel := make(map[string]int)
@@ -121,7 +176,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, g models.Graph) {
 			}
 
 			currentLevel = level
-			currentBatch = append(currentBatch, node)
+			currentBatch = append(currentBatch, ds.Label(node))
 
 			// Track counts and the first seen level per node
 			levelCounts[level]++
@@ -130,7 +185,35 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, g models.Graph) {
 			}
 		}
 
-		path, err := graph.FindShortestPath(g, request.StartNode, request.EndNode, updateCallBack)
+		// Relations switch the search from hyperlinks to typed Wikidata edges (family, colleague, ...)
+		searchGraph := ds.Graph
+		var allowed map[string]bool
+		if len(request.Relations) > 0 {
+			if ds.Relations == nil {
+				conn.WriteJSON(models.WSResponse{Type: "error", Data: "this graph has no Wikidata relations"})
+				continue
+			}
+			if allowed, err = graph.ExpandRelations(request.Relations); err != nil {
+				conn.WriteJSON(models.WSResponse{Type: "error", Data: err.Error()})
+				continue
+			}
+			searchGraph = ds.RelationGraph(allowed)
+		}
+
+		// Start and end can be a QID, the current title or an old one, the search itself runs on node IDs
+		start, end := request.StartNode, request.EndNode
+		if id, ok := ds.Resolve(start); ok {
+			start = id
+		}
+		if id, ok := ds.Resolve(end); ok {
+			end = id
+		}
+		// In the full article graph paths go through any article but start and end at people
+		if notPerson := firstNonPerson(ds, start, end); notPerson != "" {
+			conn.WriteJSON(models.WSResponse{Type: "error", Data: fmt.Sprintf("%q is not a person, searches start and end at people", ds.Label(notPerson))})
+			continue
+		}
+		path, err := graph.FindShortestPath(searchGraph, start, end, updateCallBack)
 
 		if err != nil {
 			response := models.WSResponse{
@@ -163,19 +246,37 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, g models.Graph) {
 					Type: "node_explored",
 					Data: models.NodeExplored{
 						Level:                lvl,
-						Node:                 node,
+						Node:                 ds.Label(node),
 						NodesExploredAtLevel: count,
 					},
 				})
 			}
+			found := models.PathFound{
+				Path:   ds.LabelPath(path),
+				IDs:    path,
+				Length: len(path),
+				Hops:   ds.Hops(path),     // Why each person links to the next, if the graph has link context
+				Tags:   ds.PathTags(path), // Each person's domain, if the seed list had tags
+			}
+			if allowed != nil {
+				found.Hops = graph.RelationHops(ds.Relations, allowed, path, ds.Label)
+			}
 			response := models.WSResponse{
 				Type: "path_found",
-				Data: models.PathFound{
-					Path:   path,
-					Length: len(path),
-				},
+				Data: found,
 			}
 			conn.WriteJSON(response)
 		}
 	}
 }
+
+// firstNonPerson returns the first node that's in the graph but isn't a person, "" when both are fine
+// Nodes that aren't in the graph at all are left for FindShortestPath to report
+func firstNonPerson(ds *graph.Dataset, nodes ...string) string {
+	for _, node := range nodes {
+		if ds.Graph.Has(node) && !ds.IsPerson(node) {
+			return node
+		}
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+package main
+
+// Seed list linting: checks every title in seed_names.txt against Wikipedia and reports problems by category
+// Use -fix to write a corrected seed file, exits non-zero when problems were found so it can gate CI
+// -graph checks a graph file instead: dangling edges, self-loops, duplicate neighbors, empty names and null lists
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/internal/fetcher"
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+func main() {
+	seedFile := flag.String("seeds", "seed_names.txt", "seed list to validate")
+	lang := flag.String("lang", graph.DefaultLang, "Wikipedia language edition the titles belong to")
+	fix := flag.String("fix", "", "write a corrected seed list to this file")
+	jsonOut := flag.String("json", "", "also write the full report as JSON to this file")
+	graphFile := flag.String("graph", "", "check this graph file (any format) instead of the seed list")
+	flag.Parse()
+
+	if *graphFile != "" {
+		os.Exit(validateGraph(*graphFile, *jsonOut))
+	}
+
+	// Plain text gets checked line by line (blank lines and whitespace included), structured files entry by entry
+	var lines []string
+	var seeds []fetcher.Seed
+	var err error
+	validate := fetcher.ValidateSeeds
+	if structured(*seedFile) {
+		validate = fetcher.ValidateEntries
+		seeds, err = fetcher.LoadSeeds(*seedFile)
+		for _, seed := range seeds {
+			lines = append(lines, seed.Name)
+		}
+	} else {
+		lines, err = readLines(*seedFile)
+	}
+	if err != nil {
+		log.Fatalf("failed to read seed file: %v", err)
+	}
+
+	ctx, stop := fetcher.SignalContext()
+	defer stop()
+
+	source := fetcher.NewLinkSource(*lang)
+	log.Printf("Checking %d seeds against %s", len(lines), source.Host())
+	report, err := validate(ctx, lines, source)
+	if err != nil {
+		log.Fatalf("validation failed: %v", err)
+	}
+
+	printReport(report)
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to marshal report: %v", err)
+		}
+		if err := graph.WriteFileAtomic(*jsonOut, data); err != nil {
+			log.Fatalf("failed to write %s: %v", *jsonOut, err)
+		}
+	}
+
+	if *fix != "" {
+		fixed := fixedSeeds(report, seeds)
+		if err := fetcher.WriteSeeds(*fix, fixed); err != nil {
+			log.Fatalf("failed to write %s: %v", *fix, err)
+		}
+		log.Printf("Wrote %d corrected titles to %s", len(fixed), *fix)
+	}
+
+	if len(report.Issues) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printReport lists the issues grouped by category, issues come sorted by category already
+func printReport(report *fetcher.SeedReport) {
+	units := "lines"
+	if report.Unit == "entry" {
+		units = "entries"
+	}
+	if len(report.Issues) == 0 {
+		fmt.Printf("%d %s, no problems found\n", report.Lines, units)
+		return
+	}
+
+	category := ""
+	for _, issue := range report.Issues {
+		if issue.Category != category {
+			category = issue.Category
+			fmt.Printf("\n%s (%d)\n", category, report.ByCategory[category])
+		}
+		if issue.Detail != "" {
+			fmt.Printf("  %s %d: %q %s\n", report.Unit, issue.Line, issue.Title, issue.Detail)
+		} else {
+			fmt.Printf("  %s %d: %q\n", report.Unit, issue.Line, issue.Title)
+		}
+	}
+	fmt.Printf("\n%d %s, %d problems\n", report.Lines, units, len(report.Issues))
+}
+
+// validateGraph checks a graph file and prints what's wrong with it, returns the exit code
+func validateGraph(filename, jsonOut string) int {
+	file, err := graph.LoadGraphFile(filename)
+	if err != nil {
+		log.Printf("failed to load graph: %v", err)
+		return 1
+	}
+	report, _ := graph.Validate(file.Graph, graph.Strict)
+
+	// Grouped by kind, still in node order within one
+	slices.SortStableFunc(report.Issues, func(a, b graph.GraphIssue) int {
+		return strings.Compare(a.Kind, b.Kind)
+	})
+	kind := ""
+	for _, issue := range report.Issues {
+		if issue.Kind != kind {
+			kind = issue.Kind
+			fmt.Printf("\n%s (%d)\n", kind, report.ByKind[kind])
+		}
+		fmt.Printf("  %s\n", issue)
+	}
+	fmt.Printf("\n%d nodes, %d problems\n", len(file.Graph), len(report.Issues))
+
+	if jsonOut != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Printf("failed to marshal report: %v", err)
+			return 1
+		}
+		if err := graph.WriteFileAtomic(jsonOut, data); err != nil {
+			log.Printf("failed to write %s: %v", jsonOut, err)
+			return 1
+		}
+	}
+	if len(report.Issues) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// structured reports whether the seed file carries tags (csv or json) rather than plain names
+func structured(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".csv" || ext == ".json"
+}
+
+// fixedSeeds builds the corrected seed list, keeping each seed's tags and merging the tags of duplicates
+func fixedSeeds(report *fetcher.SeedReport, seeds []fetcher.Seed) []fetcher.Seed {
+	fixed := make([]fetcher.Seed, 0, len(report.Fixed))
+	index := make(map[string]int, len(report.Fixed))
+	for _, title := range report.Fixed {
+		index[title] = len(fixed)
+		fixed = append(fixed, fetcher.Seed{Name: title})
+	}
+
+	for _, seed := range seeds {
+		resolved, ok := report.Resolved[seed.Name]
+		if !ok {
+			continue // Dropped: missing, invalid or disambiguation
+		}
+		entry := &fixed[index[resolved]]
+		for _, tag := range seed.Tags {
+			if !slices.Contains(entry.Tags, tag) {
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+	}
+	return fixed
+}
+
+func readLines(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
@@ -2,4 +2,4 @@ module github.com/Rani-Codes/sixth_degree
 
 go 1.24.5
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
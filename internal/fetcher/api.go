@@ -1,11 +1,15 @@
 package fetcher
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Rani-Codes/sixth_degree/models"
@@ -21,13 +25,72 @@ var httpClient = &http.Client{
 	},
 }
 
+// The API only accepts up to 50 titles per request for most query props
+const titlesPerRequest = 50
+
+// Batched queries run this many at a time, enough to overlap round trips without hammering the API
+const batchWorkers = 4
+
+// apiClient is the HTTP layer every MediaWiki API source shares: retries, error categories and request stats
+type apiClient struct {
+	endpoint string
+	Stats    RequestStats
+	Cache    *ResponseCache // Optional on-disk cache of responses, nil always asks the API
+}
+
+// LinkSource talks to one Wikipedia language edition (en.wikipedia.org, de.wikipedia.org, ...)
+type LinkSource struct {
+	Lang string
+	apiClient
+}
+
+// RequestStats counts what happens on the wire, safe to read while workers are fetching
+type RequestStats struct {
+	Requests    atomic.Int64 // Every HTTP attempt, retries included
+	Retries     atomic.Int64
+	RateLimited atomic.Int64 // 429 responses
+}
+
+// NewLinkSource creates a source for the given language code, empty lang defaults to English
+func NewLinkSource(lang string) *LinkSource {
+	if lang == "" {
+		lang = "en"
+	}
+	return &LinkSource{
+		Lang:      lang,
+		apiClient: apiClient{endpoint: fmt.Sprintf("https://%s.wikipedia.org/w/api.php", lang)},
+	}
+}
+
+// Host returns the wiki this source reads from, handy for logs and metadata
+func (s *LinkSource) Host() string {
+	return s.Lang + ".wikipedia.org"
+}
+
+// Page is what FetchPage gathers for one article
+type Page struct {
+	Links []string
+	RevID int64 // Latest revision ID, used by incremental refetches to skip unchanged pages
+}
+
 // FetchAllLinks gets all outbound article links from a Wikipedia page with retry logic
-func FetchAllLinks(pageTitle string) ([]string, error) {
+func (s *LinkSource) FetchAllLinks(ctx context.Context, pageTitle string) ([]string, error) {
+	page, err := s.FetchPage(ctx, pageTitle)
+	if err != nil {
+		return nil, err
+	}
+	return page.Links, nil
+}
+
+// FetchPage gets all outbound article links plus the latest revision ID of a Wikipedia page
+func (s *LinkSource) FetchPage(ctx context.Context, pageTitle string) (*Page, error) {
+	// `prop=links|info` = links plus page info (which carries lastrevid)
 	// `plnamespace=0` = only main articles
 	// `pllimit=max` = as many links as possible in one request (up to 500)
-	baseURL := "https://en.wikipedia.org/w/api.php?action=query&prop=links&format=json&plnamespace=0&pllimit=max&titles=%s"
+	baseURL := s.endpoint + "?action=query&prop=links|info&format=json&plnamespace=0&pllimit=max&titles=%s"
 
 	var allLinks []string
+	var revID int64
 	var plcontinue string //Token used for pagination, API sends this when there are more results to fetch
 
 	for {
@@ -39,24 +102,18 @@ func FetchAllLinks(pageTitle string) ([]string, error) {
 			requestURL += "&plcontinue=" + url.QueryEscape(plcontinue)
 		}
 
-		res, err := makeRequestWithRetry(requestURL)
-		if err != nil {
-			return nil, err
-		}
-		defer res.Body.Close() // Closing body to prevent resource leaks
-
-		// Ensure response is JSON
-		if !strings.Contains(res.Header.Get("Content-Type"), "application/json") {
-			return nil, fmt.Errorf("unexpected content type: %s", res.Header.Get("Content-Type"))
-		}
-
-		// Decode JSON into struct
 		var result models.WikiLinksResponse
-		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-			return nil, fmt.Errorf("failed to decode JSON: %w", err)
+		if err := s.getJSON(ctx, requestURL, &result); err != nil {
+			return nil, err
 		}
 
 		for _, page := range result.Query.Pages {
+			if page.Missing != nil {
+				return nil, &FetchError{CategoryMissing, fmt.Errorf("page %q does not exist", pageTitle)}
+			}
+			if page.Lastrevid != 0 {
+				revID = page.Lastrevid
+			}
 			for _, link := range page.Links {
 				if link.Ns == 0 { // Should be 0 because of plnamespace param
 					allLinks = append(allLinks, link.Title)
@@ -73,31 +130,319 @@ func FetchAllLinks(pageTitle string) ([]string, error) {
 		plcontinue = result.Continue.Plcontinue
 	}
 
-	return allLinks, nil
+	return &Page{Links: allLinks, RevID: revID}, nil
+}
+
+// FetchRevisions gets the latest revision ID for each title in bulk, 50 titles per request
+// Missing pages are left out of the returned map
+func (s *LinkSource) FetchRevisions(ctx context.Context, titles []string) (map[string]int64, error) {
+	revisions := make(map[string]int64, len(titles))
+
+	for start := 0; start < len(titles); start += titlesPerRequest {
+		end := min(start+titlesPerRequest, len(titles))
+		batch := titles[start:end]
+
+		requestURL := fmt.Sprintf("%s?action=query&prop=info&format=json&titles=%s",
+			s.endpoint, url.QueryEscape(strings.Join(batch, "|")))
+
+		var result models.WikiInfoResponse
+		if err := s.getJSON(ctx, requestURL, &result); err != nil {
+			return nil, err
+		}
+
+		original := make(map[string]string)
+		for _, n := range result.Query.Normalized {
+			original[n.To] = n.From
+		}
+
+		for _, page := range result.Query.Pages {
+			if page.Missing != nil {
+				continue
+			}
+			title := page.Title
+			if o, ok := original[title]; ok {
+				title = o
+			}
+			revisions[title] = page.Lastrevid
+		}
+	}
+
+	return revisions, nil
+}
+
+// TranslateTitles maps titles on this wiki to their article titles on the target language wiki using langlinks
+// Titles without an article in the target language are left out of the returned map, a redirect is translated
+// as the article it points at
+func (s *LinkSource) TranslateTitles(ctx context.Context, titles []string, targetLang string) (map[string]string, error) {
+	translated := make(map[string]string)
+
+	for start := 0; start < len(titles); start += titlesPerRequest {
+		end := min(start+titlesPerRequest, len(titles))
+		batch := titles[start:end]
+
+		requestURL := fmt.Sprintf("%s?action=query&prop=langlinks&format=json&lllimit=max&lllang=%s&redirects=1&titles=%s",
+			s.endpoint, url.QueryEscape(targetLang), url.QueryEscape(strings.Join(batch, "|")))
+
+		var llcontinue string
+		for {
+			pageURL := requestURL
+			if llcontinue != "" {
+				pageURL += "&llcontinue=" + url.QueryEscape(llcontinue)
+			}
+
+			var result models.WikiLangLinksResponse
+			if err := s.getJSON(ctx, pageURL, &result); err != nil {
+				return nil, err
+			}
+
+			// The API hands back pages under their normalized, redirected titles, map them back to what we asked for
+			// Redirects come after normalization, and a title and a redirect to it can both be in the batch
+			normalized := make(map[string]string)
+			for _, n := range result.Query.Normalized {
+				normalized[n.From] = n.To
+			}
+			redirects := make(map[string]string)
+			for _, r := range result.Query.Redirects {
+				redirects[r.From] = r.To
+			}
+			requested := make(map[string][]string) // Page title -> titles of the batch that lead to it
+			for _, title := range batch {
+				page := title
+				if to, ok := normalized[page]; ok {
+					page = to
+				}
+				if to, ok := redirects[page]; ok {
+					page = to
+				}
+				requested[page] = append(requested[page], title)
+			}
+
+			for _, page := range result.Query.Pages {
+				for _, ll := range page.Langlinks {
+					if ll.Lang != targetLang || ll.Title == "" {
+						continue
+					}
+					for _, from := range requested[page.Title] {
+						translated[from] = ll.Title
+					}
+				}
+			}
+
+			if result.Continue.Llcontinue == "" {
+				break
+			}
+			llcontinue = result.Continue.Llcontinue
+		}
+	}
+
+	return translated, nil
+}
+
+// FetchMetadata gets the page ID, short description, lead image and Wikidata QID for each title
+// The returned map is keyed by the titles as passed in, missing pages are left out
+// Batches run concurrently through a Pipeline, results are merged in input order so reruns come out the same
+func (s *LinkSource) FetchMetadata(ctx context.Context, titles []string) (map[string]models.Person, error) {
+	people := make(map[string]models.Person)
+
+	p := NewPipeline(batchWorkers, s.fetchMetadataBatch)
+	p.Ordered = true
+	err := p.Run(ctx, batches(titles, titlesPerRequest), func(r Result[[]string, map[string]models.Person]) error {
+		if r.Err != nil {
+			return r.Err
+		}
+		for title, person := range r.Out {
+			people[title] = person
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return people, nil
+}
+
+// fetchMetadataBatch runs FetchMetadata's query for up to 50 titles, following continuations
+func (s *LinkSource) fetchMetadataBatch(ctx context.Context, batch []string) (map[string]models.Person, error) {
+	people := make(map[string]models.Person, len(batch))
+
+	// `ppprop=wikibase_item` = only the Wikidata QID out of all page props
+	// `pithumbsize=320` = lead image scaled down to 320px, plenty for a search result
+	// `redirects=1` = a renamed article still gets its QID through the redirect left at the old title
+	requestURL := fmt.Sprintf("%s?action=query&prop=pageprops|pageimages|description&format=json&ppprop=wikibase_item&piprop=thumbnail&pithumbsize=320&pilimit=max&redirects=1&titles=%s",
+		s.endpoint, url.QueryEscape(strings.Join(batch, "|")))
+
+	continueParams := map[string]string{}
+	for {
+		pageURL := requestURL
+		for k, v := range continueParams {
+			pageURL += "&" + url.QueryEscape(k) + "=" + url.QueryEscape(v)
+		}
+
+		var result models.WikiPageInfoResponse
+		if err := s.getJSON(ctx, pageURL, &result); err != nil {
+			return nil, err
+		}
+
+		original := make(map[string]string)
+		for _, n := range result.Query.Normalized {
+			original[n.To] = n.From
+		}
+		// Redirects come after normalization, so walk back through both
+		for _, r := range result.Query.Redirects {
+			from := r.From
+			if o, ok := original[from]; ok {
+				from = o
+			}
+			original[r.To] = from
+		}
+
+		for _, page := range result.Query.Pages {
+			if page.Missing != nil {
+				continue
+			}
+			title := page.Title
+			if o, ok := original[title]; ok {
+				title = o
+			}
+
+			// Continuation batches only fill in some props, so merge instead of overwrite
+			person := people[title]
+			person.Name = title
+			if page.Title != title && normalizeTitle(title) != page.Title {
+				person.Title = page.Title // Renamed, title is the redirect left behind
+			}
+			if page.Pageid != 0 {
+				person.PageID = page.Pageid
+			}
+			if page.Description != "" {
+				person.Description = page.Description
+			}
+			if page.Thumbnail.Source != "" {
+				person.Thumbnail = page.Thumbnail.Source
+			}
+			if page.Pageprops.WikibaseItem != "" {
+				person.QID = page.Pageprops.WikibaseItem
+			}
+			people[title] = person
+		}
+
+		if len(result.Continue) == 0 {
+			break
+		}
+		continueParams = result.Continue
+	}
+
+	return people, nil
 }
 
-// makeRequestWithRetry handles HTTP requests with exponential backoff retry logic
-func makeRequestWithRetry(url string) (*http.Response, error) {
+// Failure categories, used to group failed fetches in the failures file
+const (
+	CategoryTimeout     = "timeout"      // Client timeout or deadline exceeded
+	CategoryNetwork     = "network"      // DNS, connection refused/reset, etc.
+	CategoryRateLimited = "rate_limited" // Still getting 429s after all retries
+	CategoryServer      = "server_error" // Still getting 5xx after all retries
+	CategoryHTTPStatus  = "http_status"  // Non-retryable status like 404 or 403
+	CategoryBadResponse = "bad_response" // Wrong content type or JSON that doesn't decode
+	CategoryMissing     = "missing_page" // The article doesn't exist (deleted or misspelled seed)
+	CategoryNotCached   = "not_cached"   // Offline mode and the response isn't in the cache
+	CategoryUnknown     = "unknown"
+)
+
+// FetchError tags a failed fetch with a category so failures can be grouped and retried
+type FetchError struct {
+	Category string
+	Err      error
+}
+
+func (e *FetchError) Error() string { return e.Err.Error() }
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// ErrorCategory returns the failure category for an error returned by the fetcher
+func ErrorCategory(err error) string {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Category
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return CategoryTimeout
+		}
+		return CategoryNetwork
+	}
+	return CategoryUnknown
+}
+
+// getJSON fetches a URL through the retry logic and decodes the JSON body into out
+func (c *apiClient) getJSON(ctx context.Context, requestURL string, out any) error {
+	res, err := c.makeRequestWithRetry(ctx, requestURL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() // Closing body to prevent resource leaks
+
+	// Ensure response is JSON
+	if !strings.Contains(res.Header.Get("Content-Type"), "application/json") {
+		return &FetchError{CategoryBadResponse, fmt.Errorf("unexpected content type: %s", res.Header.Get("Content-Type"))}
+	}
+
+	// Decode JSON into struct
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return &FetchError{CategoryBadResponse, fmt.Errorf("failed to decode JSON: %w", err)}
+	}
+	return nil
+}
+
+// makeRequestWithRetry fetches a URL, through the response cache when there is one
+func (c *apiClient) makeRequestWithRetry(ctx context.Context, url string) (*http.Response, error) {
+	if c.Cache == nil {
+		return c.sendWithRetry(ctx, url, nil)
+	}
+	return c.Cache.get(ctx, url, c.sendWithRetry)
+}
+
+// sendWithRetry handles HTTP requests with exponential backoff retry logic
+// header carries the conditional headers of a cache revalidation, which makes a 304 a valid answer
+// Cancelling ctx aborts the request and any backoff wait, the context's error comes back as is
+func (c *apiClient) sendWithRetry(ctx context.Context, url string, header http.Header) (*http.Response, error) {
 	maxRetries := 3
 	baseDelay := 1 * time.Second
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		req, err := http.NewRequest("GET", url, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		// Setting User-Agent to be respectful to Wikipedia
 		req.Header.Set("User-Agent", "SixDegreeBot/1.0 (Educational Project)")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		c.Stats.Requests.Add(1)
+		if attempt > 0 {
+			c.Stats.Retries.Add(1)
+		}
 
 		res, err := httpClient.Do(req)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			if attempt == maxRetries-1 {
-				return nil, fmt.Errorf("request failed after %d attempts: %w", maxRetries, err)
+				category := CategoryNetwork
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					category = CategoryTimeout
+				}
+				return nil, &FetchError{category, fmt.Errorf("request failed after %d attempts: %w", maxRetries, err)}
 			}
 			// Wait before retrying (exponential backoff)
 			delay := baseDelay * time.Duration(1<<attempt) // 1s, 2s, 4s
-			time.Sleep(delay)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
@@ -105,9 +450,16 @@ func makeRequestWithRetry(url string) (*http.Response, error) {
 		if res.StatusCode == http.StatusTooManyRequests || // 429 Rate Limited
 			res.StatusCode >= 500 { // 5xx Server Errors
 			res.Body.Close() // Close before retry
+			if res.StatusCode == http.StatusTooManyRequests {
+				c.Stats.RateLimited.Add(1)
+			}
 
 			if attempt == maxRetries-1 {
-				return nil, fmt.Errorf("request failed with status %d after %d attempts", res.StatusCode, maxRetries)
+				category := CategoryServer
+				if res.StatusCode == http.StatusTooManyRequests {
+					category = CategoryRateLimited
+				}
+				return nil, &FetchError{category, fmt.Errorf("request failed with status %d after %d attempts", res.StatusCode, maxRetries)}
 			}
 
 			// For rate limiting, wait longer
@@ -115,14 +467,16 @@ func makeRequestWithRetry(url string) (*http.Response, error) {
 			if res.StatusCode == http.StatusTooManyRequests {
 				delay *= 2 // Double delay for rate limits
 			}
-			time.Sleep(delay)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		// Check for non-200 status codes that shouldn't be retried
-		if res.StatusCode != http.StatusOK {
+		if res.StatusCode != http.StatusOK && (header == nil || res.StatusCode != http.StatusNotModified) {
 			res.Body.Close()
-			return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+			return nil, &FetchError{CategoryHTTPStatus, fmt.Errorf("unexpected status code: %d", res.StatusCode)}
 		}
 
 		return res, nil
@@ -130,3 +484,15 @@ func makeRequestWithRetry(url string) (*http.Response, error) {
 
 	return nil, fmt.Errorf("unexpected: reached end of retry loop")
 }
+
+// sleep waits for d, or less if ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// langlinksFixture serves prop=langlinks the way the API does: titles are normalized first, then followed through
+// redirects when redirects=1 is set, otherwise the redirect page comes back with no langlinks of its own
+func langlinksFixture(t *testing.T, redirects map[string]string, langlinks map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("prop") != "langlinks" {
+			http.Error(w, "unexpected prop "+q.Get("prop"), http.StatusBadRequest)
+			return
+		}
+		query := map[string]any{}
+		var normalized, redirected []map[string]string
+		pages := make(map[string]any)
+		for i, title := range strings.Split(q.Get("titles"), "|") {
+			if n := normalizeTitle(title); n != title {
+				normalized = append(normalized, map[string]string{"from": title, "to": n})
+				title = n
+			}
+			if to, ok := redirects[title]; ok && q.Get("redirects") == "1" {
+				redirected = append(redirected, map[string]string{"from": title, "to": to})
+				title = to
+			}
+			page := map[string]any{"title": title}
+			if ll, ok := langlinks[title]; ok {
+				page["langlinks"] = []map[string]string{{"lang": q.Get("lllang"), "*": ll}}
+			}
+			pages[fmt.Sprint(i+1)] = page
+		}
+		query["pages"] = pages
+		if normalized != nil {
+			query["normalized"] = normalized
+		}
+		if redirected != nil {
+			query["redirects"] = redirected
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]any{"query": query})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTranslateTitles(t *testing.T) {
+	srv := langlinksFixture(t,
+		map[string]string{"Marie Sklodowska-Curie": "Marie Curie", "Einstein": "Albert Einstein"},
+		map[string]string{"Marie Curie": "Maria Skłodowska-Curie", "Albert Einstein": "Albert Einstein", "Pierre Curie": "Pierre Curie"},
+	)
+	source := NewLinkSource("en")
+	source.endpoint = srv.URL
+
+	// A redirect, a title that needs normalizing on top of one, a title next to a redirect to it and one
+	// without a Polish article
+	titles := []string{"Marie Sklodowska-Curie", "einstein", "Albert Einstein", "pierre_Curie", "Eve Curie"}
+	translated, err := source.TranslateTitles(context.Background(), titles, "pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Marie Sklodowska-Curie": "Maria Skłodowska-Curie",
+		"einstein":               "Albert Einstein",
+		"Albert Einstein":        "Albert Einstein",
+		"pierre_Curie":           "Pierre Curie",
+	}
+	if !reflect.DeepEqual(translated, want) {
+		t.Errorf("translated = %v, want %v", translated, want)
+	}
+}
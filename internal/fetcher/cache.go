@@ -0,0 +1,216 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+// ResponseCache keeps API responses on disk so a rerun (new filtering, new link classification) doesn't
+// refetch all 10k pages. Bodies are content addressed: blobs/<sha256 of the body>, and each URL has a
+// small entry under entries/<sha256 of the URL> pointing at its body plus the validators to revalidate it.
+//
+// Entries younger than TTL are served straight from disk, older ones are revalidated with If-None-Match /
+// If-Modified-Since and a 304 keeps the stored body. Offline never touches the network. Only JSON answers
+// without an API error are stored (see cacheable).
+type ResponseCache struct {
+	dir     string
+	TTL     time.Duration
+	Offline bool
+	Stats   CacheStats
+}
+
+// CacheStats counts how requests were answered
+type CacheStats struct {
+	Hits        atomic.Int64 // Fresh (or offline) entries served from disk
+	Revalidated atomic.Int64 // Stale entries the API confirmed with a 304
+	Misses      atomic.Int64 // Fetched from the API and stored
+}
+
+// cacheEntry is what's stored per URL
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	Body         string    `json:"body"` // sha256 of the body, names its blob
+	ContentType  string    `json:"contentType"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	StoredAt     time.Time `json:"storedAt"` // Last time the API gave us (or confirmed) this body
+}
+
+// NewResponseCache opens (creating if needed) a cache directory
+func NewResponseCache(dir string, ttl time.Duration) (*ResponseCache, error) {
+	for _, sub := range []string{"entries", "blobs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	return &ResponseCache{dir: dir, TTL: ttl}, nil
+}
+
+// Summary is a one line account of the cache's work, for the end of a run
+func (c *ResponseCache) Summary() string {
+	return fmt.Sprintf("cache: %d hits, %d revalidated, %d fetched", c.Stats.Hits.Load(), c.Stats.Revalidated.Load(), c.Stats.Misses.Load())
+}
+
+// get answers a request from the cache when it can and through send when it can't
+func (c *ResponseCache) get(ctx context.Context, url string, send func(context.Context, string, http.Header) (*http.Response, error)) (*http.Response, error) {
+	entry := c.load(url)
+	var cached []byte
+	if entry != nil {
+		var err error
+		if cached, err = c.readBlob(entry.Body); err != nil {
+			entry = nil // Body went missing, fetch it again without validators so the API sends it
+		}
+	}
+	if entry != nil && (c.Offline || time.Since(entry.StoredAt) < c.TTL) {
+		c.Stats.Hits.Add(1)
+		return cachedResponse(entry, cached), nil
+	}
+	if c.Offline {
+		return nil, &FetchError{CategoryNotCached, fmt.Errorf("offline and not cached: %s", url)}
+	}
+
+	var header http.Header
+	if entry != nil && (entry.ETag != "" || entry.LastModified != "") {
+		header = http.Header{}
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := send(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		if entry == nil {
+			return nil, &FetchError{CategoryBadResponse, fmt.Errorf("got 304 for a request that wasn't conditional: %s", url)}
+		}
+		entry.StoredAt = time.Now().UTC()
+		c.save(entry)
+		c.Stats.Revalidated.Add(1)
+		return cachedResponse(entry, cached), nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, &FetchError{CategoryNetwork, fmt.Errorf("failed to read response: %w", err)}
+	}
+	c.Stats.Misses.Add(1)
+	if cacheable(res.Header, body) {
+		c.store(url, res.Header, body)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// cacheable reports whether a response is worth keeping: JSON without a top level error. MediaWiki sends maxlag,
+// ratelimited and the like as a 200 with an error object and a proxy can answer with an HTML page, kept they'd be
+// served until the TTL runs out, and forever offline.
+func cacheable(header http.Header, body []byte) bool {
+	if !strings.Contains(header.Get("Content-Type"), "application/json") {
+		return false
+	}
+	var top struct {
+		Error json.RawMessage `json:"error"`
+	}
+	return json.Unmarshal(body, &top) == nil && top.Error == nil
+}
+
+// store writes a fresh response, a cache that can't write just means the next run refetches
+func (c *ResponseCache) store(url string, header http.Header, body []byte) {
+	sum := sha256.Sum256(body)
+	blob := hex.EncodeToString(sum[:])
+	blobPath := c.path("blobs", blob)
+	if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+		if err := writeCacheFile(blobPath, body); err != nil {
+			log.Printf("Couldn't cache %s: %v", url, err)
+			return
+		}
+	}
+
+	c.save(&cacheEntry{
+		URL:          url,
+		Body:         blob,
+		ContentType:  header.Get("Content-Type"),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		StoredAt:     time.Now().UTC(),
+	})
+}
+
+// save writes the entry for a URL
+func (c *ResponseCache) save(entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = writeCacheFile(c.path("entries", urlKey(entry.URL)), data)
+	}
+	if err != nil {
+		log.Printf("Couldn't cache %s: %v", entry.URL, err)
+	}
+}
+
+// load reads the entry for a URL, nil if there isn't a usable one
+func (c *ResponseCache) load(url string) *cacheEntry {
+	data, err := os.ReadFile(c.path("entries", urlKey(url)))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	// A different URL means a hash collision, treat it as a miss
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	return &entry
+}
+
+func (c *ResponseCache) readBlob(name string) ([]byte, error) {
+	return os.ReadFile(c.path("blobs", name))
+}
+
+// path spreads files over 256 subdirectories by the first two hex digits, one flat directory of 10k+ files is slow
+func (c *ResponseCache) path(kind, name string) string {
+	return filepath.Join(c.dir, kind, name[:2], name)
+}
+
+// urlKey is the file name for a URL's entry
+func urlKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeCacheFile writes atomically so a killed run never leaves half a response behind
+func writeCacheFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return graph.WriteFileAtomic(path, data)
+}
+
+// cachedResponse dresses a stored body up as the 200 the API originally sent
+func cachedResponse(entry *cacheEntry, body []byte) *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{entry.ContentType}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
@@ -0,0 +1,232 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// apiFixture answers every request with body and an ETag, and a 304 when the request carries that ETag
+// requests counts what reached it, conditional ones in revalidations
+type apiFixture struct {
+	contentType   string
+	body          string
+	requests      atomic.Int32
+	revalidations atomic.Int32
+}
+
+func (f *apiFixture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	if r.Header.Get("If-None-Match") != "" {
+		f.revalidations.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("ETag", `"v1"`)
+	io.WriteString(w, f.body)
+}
+
+// send is the bare minimum of sendWithRetry: one request, the cache's conditional headers included
+func send(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// fetch gets url through the cache and reads the whole body
+func fetch(t *testing.T, c *ResponseCache, url string) (string, error) {
+	t.Helper()
+	res, err := c.get(context.Background(), url, send)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status %d", res.StatusCode)
+	}
+	return string(body), nil
+}
+
+const einsteinLinks = `{"query":{"pages":{"736":{"title":"Albert Einstein","links":[{"title":"Mileva Marić"}]}}}}`
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		setup func(t *testing.T, c *ResponseCache, url string) // Between the first and second request
+		// What the second request does
+		requests, revalidations int32
+		hits, revalidated       int64
+	}{
+		{
+			name:     "fresh entry comes from disk",
+			ttl:      time.Hour,
+			requests: 1,
+			hits:     1,
+		},
+		{
+			name:          "stale entry is revalidated by a 304",
+			ttl:           0,
+			requests:      2,
+			revalidations: 1,
+			revalidated:   1,
+		},
+		{
+			name: "stale entry whose body went missing is fetched whole",
+			ttl:  0,
+			setup: func(t *testing.T, c *ResponseCache, url string) {
+				if err := os.Remove(c.path("blobs", c.load(url).Body)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			requests: 2,
+		},
+		{
+			name: "fresh entry whose body went missing is fetched whole",
+			ttl:  time.Hour,
+			setup: func(t *testing.T, c *ResponseCache, url string) {
+				if err := os.Remove(c.path("blobs", c.load(url).Body)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			requests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &apiFixture{contentType: "application/json; charset=utf-8", body: einsteinLinks}
+			srv := httptest.NewServer(api)
+			defer srv.Close()
+			c, err := NewResponseCache(t.TempDir(), tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			url := srv.URL + "/w/api.php?action=query&titles=Albert+Einstein"
+
+			if body, err := fetch(t, c, url); err != nil || body != einsteinLinks {
+				t.Fatalf("first request: %q, %v", body, err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, c, url)
+			}
+			if body, err := fetch(t, c, url); err != nil || body != einsteinLinks {
+				t.Fatalf("second request: %q, %v", body, err)
+			}
+
+			if api.requests.Load() != tt.requests || api.revalidations.Load() != tt.revalidations {
+				t.Errorf("API got %d requests, %d conditional, want %d and %d", api.requests.Load(), api.revalidations.Load(), tt.requests, tt.revalidations)
+			}
+			if c.Stats.Hits.Load() != tt.hits || c.Stats.Revalidated.Load() != tt.revalidated {
+				t.Errorf("%s, want %d hits and %d revalidated", c.Summary(), tt.hits, tt.revalidated)
+			}
+		})
+	}
+}
+
+// A 304 only makes sense for a request the cache made conditional, with nothing to fall back on it's an error
+func TestResponseCacheUnexpected304(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer srv.Close()
+	c, err := NewResponseCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetch(t, c, srv.URL); ErrorCategory(err) != CategoryBadResponse {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestResponseCacheOffline(t *testing.T) {
+	api := &apiFixture{contentType: "application/json", body: einsteinLinks}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	dir := t.TempDir()
+	online, err := NewResponseCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := srv.URL + "/w/api.php?titles=Albert+Einstein"
+	if _, err := fetch(t, online, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	offline, err := NewResponseCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline.Offline = true
+	// However old, a stored response is served
+	if body, err := fetch(t, offline, stored); err != nil || body != einsteinLinks {
+		t.Errorf("stored response: %q, %v", body, err)
+	}
+	if _, err := fetch(t, offline, srv.URL+"/w/api.php?titles=Mileva+Mari%C4%87"); ErrorCategory(err) != CategoryNotCached {
+		t.Errorf("uncached response: err = %v", err)
+	}
+
+	// Another URL whose key is the same: the entry names the URL it's for, so it's a miss and not a wrong answer
+	collision := srv.URL + "/w/api.php?titles=Isaac+Newton"
+	data, err := os.ReadFile(online.path("entries", urlKey(stored)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCacheFile(online.path("entries", urlKey(collision)), data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetch(t, offline, collision); ErrorCategory(err) != CategoryNotCached {
+		t.Errorf("colliding entry: err = %v", err)
+	}
+	if api.requests.Load() != 1 {
+		t.Errorf("offline cache made %d requests", api.requests.Load()-1)
+	}
+}
+
+// Responses that only look like a 200 are handed on but not kept, or offline mode would serve them forever
+func TestResponseCacheSkipsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		stored      bool
+	}{
+		{"maxlag", "application/json; charset=utf-8", `{"error":{"code":"maxlag","info":"Waiting for a database server: 6 seconds lagged."},"servedby":"mw1"}`, false},
+		{"rate limited", "application/json", `{"error":{"code":"ratelimited","info":"You've exceeded your rate limit."}}`, false},
+		{"html error page", "text/html; charset=utf-8", `<html><body>Wikimedia Error</body></html>`, false},
+		{"not json", "application/json", `<html>`, false},
+		{"an error inside a page is fine", "application/json", `{"query":{"pages":{"1":{"title":"Error","links":[]}}}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&apiFixture{contentType: tt.contentType, body: tt.body})
+			defer srv.Close()
+			c, err := NewResponseCache(t.TempDir(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body, err := fetch(t, c, srv.URL); err != nil || body != tt.body {
+				t.Fatalf("caller got %q, %v", body, err)
+			}
+			if stored := c.load(srv.URL) != nil; stored != tt.stored {
+				t.Errorf("stored = %v, want %v", stored, tt.stored)
+			}
+		})
+	}
+}
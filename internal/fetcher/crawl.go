@@ -0,0 +1,211 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Crawler builds the full article graph: the seeds plus every article within Depth links of one
+// Unlike WorkerPool it keeps links to any article, so paths can go through universities, cities, bands, ...
+// Nodes are numbered as they're discovered and links stored by number, titles are only kept once.
+type Crawler struct {
+	numWorkers int
+	source     *LinkSource
+	outFile    string
+
+	Depth    int // 0 fetches only the seeds, 1 also every article a seed links to, ...
+	MaxPages int // Stop queueing pages once this many are known, 0 means no cap
+
+	index    map[string]int32
+	g        graph.CompactGraph
+	fetched  []bool
+	queued   []bool
+	failures map[string]int // Category -> pages
+	progress *Progress
+}
+
+// NewCrawler creates a crawler that writes the compact full article graph to outFile
+func NewCrawler(numWorkers int, source *LinkSource, outFile string) *Crawler {
+	return &Crawler{
+		numWorkers: numWorkers,
+		source:     source,
+		outFile:    outFile,
+		Depth:      1,
+		index:      make(map[string]int32),
+		failures:   make(map[string]int),
+	}
+}
+
+// node returns the number of a title, adding it when add is set, ok is false for unknown titles otherwise
+func (c *Crawler) node(title string, add bool) (int32, bool) {
+	if i, ok := c.index[title]; ok {
+		return i, true
+	}
+	if !add {
+		return 0, false
+	}
+	i := int32(len(c.g.Names))
+	c.index[title] = i
+	c.g.Names = append(c.g.Names, title)
+	c.g.People = append(c.g.People, false)
+	c.g.Links = append(c.g.Links, nil)
+	c.fetched = append(c.fetched, false)
+	c.queued = append(c.queued, false)
+	return i, true
+}
+
+// Run crawls level by level from the seed file and writes the graph, returning how many pages failed
+// A cancelled crawl writes nothing and returns the context's error
+func (c *Crawler) Run(ctx context.Context, seedFile string) (int, error) {
+	seeds, err := LoadSeeds(seedFile)
+	if err != nil {
+		return 0, err
+	}
+	seedHash, err := hashFile(seedFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash seed file: %w", err)
+	}
+
+	var frontier []int32
+	for _, seed := range seeds {
+		i, _ := c.node(seed.Name, true)
+		c.g.People[i] = true
+		if !c.queued[i] {
+			c.queued[i] = true
+			frontier = append(frontier, i)
+		}
+	}
+
+	c.progress = NewProgress(&c.source.Stats)
+	c.progress.Start()
+	for depth := 0; depth <= c.Depth && len(frontier) > 0; depth++ {
+		last := depth == c.Depth
+		log.Printf("Crawl depth %d: fetching %d pages (%d known)", depth, len(frontier), len(c.g.Names))
+		frontier, err = c.fetchLevel(ctx, frontier, last)
+		if err != nil {
+			break
+		}
+	}
+	c.progress.Stop()
+	if err != nil {
+		return 0, err
+	}
+
+	full := c.prune()
+	meta := models.GraphMeta{
+		FetchedAt: time.Now().UTC(),
+		SeedFile:  filepath.Base(seedFile),
+		SeedHash:  seedHash,
+		Source:    c.source.Host(),
+		Failed:    c.failed(),
+	}
+	if err := graph.WriteCompactGraph(c.outFile, meta, full); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", c.outFile, err)
+	}
+	edges := 0
+	for _, links := range full.Links {
+		edges += len(links)
+	}
+	log.Printf("Wrote full article graph with %d pages and %d links to %s (%d failed %v)",
+		len(full.Names), edges, c.outFile, meta.Failed, c.failures)
+	return meta.Failed, nil
+}
+
+// fetchLevel fetches one level of the crawl and returns the next one
+// On the last level links only count when they point at a page we're fetching anyway, anything
+// else would be a dead end with no links of its own
+func (c *Crawler) fetchLevel(ctx context.Context, frontier []int32, last bool) ([]int32, error) {
+	c.progress.AddTotal(len(frontier))
+
+	// Titles are read up front, workers never touch the crawler's slices while the sink grows them
+	titleOf := make(map[int32]string, len(frontier))
+	for _, n := range frontier {
+		titleOf[n] = c.g.Names[n]
+	}
+	p := NewPipeline(c.numWorkers, func(ctx context.Context, n int32) ([]string, error) {
+		return c.source.FetchAllLinks(ctx, titleOf[n])
+	})
+
+	var next []int32
+	err := p.Run(ctx, slices.Values(frontier), func(r Result[int32, []string]) error {
+		if r.Err != nil {
+			c.progress.Failure()
+			c.failures[ErrorCategory(r.Err)]++
+			log.Printf("Error on %s: %v", c.g.Names[r.Job], r.Err)
+			return nil
+		}
+		c.progress.Success()
+		c.fetched[r.Job] = true
+
+		links := make([]int32, 0, len(r.Out))
+		for _, title := range r.Out {
+			add := !last && (c.MaxPages == 0 || len(c.g.Names) < c.MaxPages)
+			to, ok := c.node(title, add)
+			if !ok {
+				continue
+			}
+			links = append(links, to)
+			if !last && !c.queued[to] {
+				c.queued[to] = true
+				next = append(next, to)
+			}
+		}
+		c.g.Links[r.Job] = links
+		return nil
+	})
+	return next, err
+}
+
+// prune drops the pages that never got fetched (failed, or past the cap) and renumbers the rest
+func (c *Crawler) prune() *graph.CompactGraph {
+	renumber := make([]int32, len(c.g.Names))
+	full := &graph.CompactGraph{}
+	for i, name := range c.g.Names {
+		renumber[i] = -1
+		if c.fetched[i] {
+			renumber[i] = int32(len(full.Names))
+			full.Names = append(full.Names, name)
+			full.People = append(full.People, c.g.People[i])
+		}
+	}
+	for i, links := range c.g.Links {
+		if !c.fetched[i] {
+			continue
+		}
+		kept := make([]int32, 0, len(links))
+		for _, to := range links {
+			if r := renumber[to]; r >= 0 && int(to) != i {
+				kept = append(kept, r)
+			}
+		}
+		full.Links = append(full.Links, kept)
+	}
+	return full
+}
+
+// Pages is the number of pages the crawl tried to fetch
+func (c *Crawler) Pages() int {
+	total := 0
+	for _, q := range c.queued {
+		if q {
+			total++
+		}
+	}
+	return total
+}
+
+// failed is the number of pages that couldn't be fetched
+func (c *Crawler) failed() int {
+	total := 0
+	for _, n := range c.failures {
+		total += n
+	}
+	return total
+}
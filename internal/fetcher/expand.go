@@ -0,0 +1,346 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Tag given to people added by expansion, so they can be told apart from hand picked seeds
+const TagExpanded = "expanded"
+
+// Wikidata's "instance of" property and its "human" item
+const (
+	propInstanceOf = "P31"
+	itemHuman      = "Q5"
+)
+
+// Expansion is one person added to the seeds by an expansion round, and why
+type Expansion struct {
+	Name       string   `json:"name"`
+	Round      int      `json:"round"`
+	QID        string   `json:"qid,omitempty"`
+	LinkedFrom int      `json:"linkedFrom"` // Fetched pages linking to it when it got picked
+	Examples   []string `json:"examples"`   // A few of those pages
+	Reason     string   `json:"reason"`     // How we know it's a person
+}
+
+// ExpansionReport is what the expansion file holds
+type ExpansionReport struct {
+	Rounds   int         `json:"rounds"`   // Rounds that added someone
+	Checked  int         `json:"checked"`  // Candidates looked up
+	Rejected int         `json:"rejected"` // Candidates that aren't people
+	Capped   bool        `json:"capped"`   // Stopped early because the graph hit the size cap
+	Added    []Expansion `json:"added"`
+}
+
+// candidate is a page fetched articles link to that isn't a seed yet
+type candidate struct {
+	title      string
+	linkedFrom []string
+}
+
+// expanding reports whether links to non-seed pages need to be kept for expansion rounds
+func (wp *WorkerPool) expanding() bool {
+	return wp.ExpandRounds > 0
+}
+
+// expand runs one expansion round: ranks the pages fetched articles link to by how many of them do,
+// keeps the top ExpandTop that are people and returns them as jobs for the next pass
+func (wp *WorkerPool) expand(ctx context.Context, round int) []JobRequest {
+	want := wp.ExpandTop
+	if wp.MaxNodes > 0 {
+		if room := wp.MaxNodes - len(wp.validNames); room < want {
+			want = room
+			wp.expansion.Capped = true
+		}
+	}
+	if want <= 0 {
+		return nil
+	}
+
+	candidates := wp.candidates()
+	if len(candidates) == 0 {
+		return nil
+	}
+	// Most linked pages are places, institutions and events, so look at a few times more than we need
+	candidates = candidates[:min(len(candidates), want*4)]
+	titles := make([]string, len(candidates))
+	for i, c := range candidates {
+		titles[i] = c.title
+	}
+
+	log.Printf("Expansion round %d: checking the %d most linked pages for people", round, len(titles))
+	people, err := wp.detectPeople(ctx, titles)
+	if err != nil {
+		log.Printf("Expansion round %d failed, keeping the graph as is: %v", round, err)
+		return nil
+	}
+	wp.expansion.Checked += len(titles)
+
+	var jobs []JobRequest
+	redirected := false
+	for _, c := range candidates {
+		if len(jobs) >= want {
+			break // The rest stay candidates for the next round
+		}
+		found, ok := people[c.title]
+		if !ok {
+			wp.rejected[c.title] = true
+			wp.expansion.Rejected++
+			continue
+		}
+		// A link through a redirect gets the article's current title, which may already be a seed
+		if found.Name != c.title {
+			wp.redirects[c.title] = found.Name
+			redirected = true
+		}
+		if wp.validNames[found.Name] {
+			continue
+		}
+
+		wp.validNames[found.Name] = true
+		wp.tags[found.Name] = append(wp.tags[found.Name], TagExpanded)
+		found.Round = round
+		found.LinkedFrom = len(c.linkedFrom)
+		found.Examples = c.linkedFrom[:min(len(c.linkedFrom), 5)]
+		wp.expansion.Added = append(wp.expansion.Added, found)
+		jobs = append(jobs, JobRequest{Name: found.Name, Title: found.Name})
+	}
+
+	if len(jobs) > 0 {
+		wp.expansion.Rounds++
+	}
+	if len(jobs) > 0 || redirected {
+		wp.promoteLinks()
+	}
+	log.Printf("Expansion round %d: adding %d people (%d seeds so far)", round, len(jobs), len(wp.validNames))
+	return jobs
+}
+
+// resolve returns the article a link title leads to, following the redirects expansion has come across
+func (wp *WorkerPool) resolve(title string) string {
+	if to, ok := wp.redirects[title]; ok {
+		return to
+	}
+	return title
+}
+
+// candidates lists non-seed pages by how many fetched pages link to them, most linked first
+// Links through a redirect count towards the article it leads to
+func (wp *WorkerPool) candidates() []candidate {
+	linkedFrom := make(map[string][]string)
+	for name, others := range wp.others {
+		for _, title := range others {
+			to := wp.resolve(title)
+			if !wp.validNames[to] && !wp.rejected[title] && !wp.rejected[to] && !slices.Contains(linkedFrom[to], name) {
+				linkedFrom[to] = append(linkedFrom[to], name)
+			}
+		}
+	}
+
+	candidates := make([]candidate, 0, len(linkedFrom))
+	for title, from := range linkedFrom {
+		sort.Strings(from)
+		candidates = append(candidates, candidate{title: title, linkedFrom: from})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if len(a.linkedFrom) != len(b.linkedFrom) {
+			return len(a.linkedFrom) > len(b.linkedFrom)
+		}
+		return a.title < b.title
+	})
+	return candidates
+}
+
+// promoteLinks moves links to pages that just became seeds from the side list into the graph,
+// pages fetched in earlier rounds link to the new people too. A link through a redirect goes in under the seed's name.
+func (wp *WorkerPool) promoteLinks() {
+	for name, others := range wp.others {
+		kept := others[:0]
+		for _, title := range others {
+			if to := wp.resolve(title); wp.validNames[to] {
+				if to != name && !slices.Contains(wp.graph[name], to) {
+					wp.graph[name] = append(wp.graph[name], to)
+				}
+				if ctx, ok := wp.contexts[name][title]; ok && to != title {
+					wp.contexts[name][to] = ctx // Contexts are keyed like the graph
+				}
+			} else {
+				kept = append(kept, title)
+			}
+		}
+		wp.others[name] = kept
+	}
+}
+
+// detectPeople finds which titles are about a person: Wikidata "instance of: human" when the page has an item,
+// biography categories (births, deaths, living people) otherwise. Keys are the titles as passed in.
+func (wp *WorkerPool) detectPeople(ctx context.Context, titles []string) (map[string]Expansion, error) {
+	meta, err := wp.source.FetchMetadata(ctx, titles)
+	if err != nil {
+		return nil, err
+	}
+
+	people := make(map[string]Expansion)
+	var noItem []string
+	qids := make([]string, 0, len(meta))
+	for _, title := range titles {
+		if p, ok := meta[title]; ok && p.QID != "" {
+			qids = append(qids, p.QID)
+		} else if ok {
+			noItem = append(noItem, title)
+		}
+	}
+
+	humans, err := wp.Wikidata.FetchHumans(ctx, qids)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		// Categories are a fine stand in when Wikidata is down
+		log.Printf("Wikidata check failed, falling back to categories: %v", err)
+		humans = nil
+		noItem = noItem[:0]
+		for _, title := range titles {
+			if _, ok := meta[title]; ok {
+				noItem = append(noItem, title)
+			}
+		}
+	}
+	for _, title := range titles {
+		p := meta[title]
+		if humans[p.QID] {
+			people[title] = Expansion{Name: current(title, p), QID: p.QID, Reason: "wikidata instance of human (Q5)"}
+		}
+	}
+
+	if len(noItem) > 0 {
+		categories, err := wp.source.FetchBiographyCategories(ctx, noItem)
+		if err != nil {
+			return nil, err
+		}
+		for title, category := range categories {
+			p := meta[title]
+			people[title] = Expansion{Name: current(title, p), QID: p.QID, Reason: "category: " + category}
+		}
+	}
+	return people, nil
+}
+
+// current is the article title a link leads to, following a redirect if there is one
+func current(title string, p models.Person) string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return normalizeTitle(title)
+}
+
+// writeExpansion writes the expansion report
+func (wp *WorkerPool) writeExpansion() error {
+	if wp.expansion.Added == nil {
+		wp.expansion.Added = []Expansion{} // [] instead of null in the JSON
+	}
+	data, err := json.MarshalIndent(wp.expansion, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal expansion report: %w", err)
+	}
+	if err := graph.WriteFileAtomic(wp.ExpansionFile, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", wp.ExpansionFile, err)
+	}
+	log.Printf("Wrote %d people added by expansion to %s", len(wp.expansion.Added), wp.ExpansionFile)
+	return nil
+}
+
+// FetchHumans reports which of the given items are an instance of human (Q5), 50 per request
+func (s *WikidataSource) FetchHumans(ctx context.Context, ids []string) (map[string]bool, error) {
+	humans := make(map[string]bool)
+	for start := 0; start < len(ids); start += titlesPerRequest {
+		end := min(start+titlesPerRequest, len(ids))
+		requestURL := fmt.Sprintf("%s?action=wbgetentities&props=claims&format=json&ids=%s",
+			s.endpoint, url.QueryEscape(strings.Join(ids[start:end], "|")))
+
+		var result models.WikidataEntitiesResponse
+		if err := s.getJSON(ctx, requestURL, &result); err != nil {
+			return nil, err
+		}
+		if result.Error != nil {
+			return nil, &FetchError{CategoryBadResponse, fmt.Errorf("wikidata API error %s: %s", result.Error.Code, result.Error.Info)}
+		}
+
+		for qid, entity := range result.Entities {
+			for _, st := range entity.Claims[propInstanceOf] {
+				if item, ok := entityID(st.Mainsnak); ok && item == itemHuman {
+					humans[qid] = true
+					break
+				}
+			}
+		}
+	}
+	return humans, nil
+}
+
+// FetchBiographyCategories returns, for each title in a biography category, the category that gave it away
+// ("Living people", "1879 births", "1955 deaths"). Titles that aren't biographies are left out.
+func (s *LinkSource) FetchBiographyCategories(ctx context.Context, titles []string) (map[string]string, error) {
+	found := make(map[string]string)
+
+	for start := 0; start < len(titles); start += titlesPerRequest {
+		end := min(start+titlesPerRequest, len(titles))
+		requestURL := fmt.Sprintf("%s?action=query&prop=categories&format=json&cllimit=max&redirects=1&titles=%s",
+			s.endpoint, url.QueryEscape(strings.Join(titles[start:end], "|")))
+
+		continueParams := map[string]string{}
+		for {
+			pageURL := requestURL
+			for k, v := range continueParams {
+				pageURL += "&" + url.QueryEscape(k) + "=" + url.QueryEscape(v)
+			}
+
+			var result models.WikiCategoriesResponse
+			if err := s.getJSON(ctx, pageURL, &result); err != nil {
+				return nil, err
+			}
+			original := make(map[string]string)
+			for _, n := range result.Query.Normalized {
+				original[n.To] = n.From
+			}
+			for _, r := range result.Query.Redirects {
+				from := r.From
+				if o, ok := original[from]; ok {
+					from = o
+				}
+				original[r.To] = from
+			}
+
+			for _, page := range result.Query.Pages {
+				title := page.Title
+				if o, ok := original[title]; ok {
+					title = o
+				}
+				for _, c := range page.Categories {
+					name := strings.TrimPrefix(c.Title, "Category:")
+					if name == "Living people" || strings.HasSuffix(name, " births") || strings.HasSuffix(name, " deaths") {
+						found[title] = name
+						break
+					}
+				}
+			}
+
+			if len(result.Continue) == 0 {
+				break
+			}
+			continueParams = result.Continue
+		}
+	}
+	return found, nil
+}
@@ -1,24 +1,21 @@
 package fetcher
 
 import (
-	"bufio"
-	"log"
-	"os"
+	"fmt"
 )
 
-func LoadValidNames(filename string) map[string]bool {
+// LoadValidNames reads any seed format (see LoadSeeds) into a set of names
+func LoadValidNames(filename string) (map[string]bool, error) {
 	validNames := make(map[string]bool)
 
-	file, err := os.Open(filename)
+	seeds, err := LoadSeeds(filename)
 	if err != nil {
-		log.Fatal("Couldn't load data into ValidNames. Failed to open seed file: ", err)
+		return nil, fmt.Errorf("couldn't load data into ValidNames, failed to open seed file: %w", err)
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		validNames[scanner.Text()] = true
+	for _, seed := range seeds {
+		validNames[seed.Name] = true
 	}
 
-	return validNames
+	return validNames, nil
 }
@@ -0,0 +1,198 @@
+package fetcher
+
+import (
+	"context"
+	"iter"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Pipeline is the fetch loop every command shares: jobs come from a source, numWorkers workers run them and
+// the results go to a sink, as they finish or in input order. Cancelling the context stops feeding new jobs,
+// aborts the requests in flight and makes Run return the context's error.
+type Pipeline[In, Out any] struct {
+	numWorkers int
+	work       func(context.Context, In) (Out, error)
+
+	Ordered    bool                                 // Hand results to the sink in input order instead of as they finish
+	Retries    int                                  // Extra attempts for a job that failed with a retryable error
+	RetryDelay time.Duration                        // Wait before each retry, the workers keep going with other jobs meanwhile
+	Retryable  func(error) bool                     // Which errors are worth another attempt, defaults to Retryable
+	OnRetry    func(job In, err error, attempt int) // Called (from a worker) when a failed attempt gets queued again
+}
+
+// Result is one finished job, Err is the error of its last attempt
+type Result[In, Out any] struct {
+	Index    int // Position of the job in the source
+	Job      In
+	Out      Out
+	Err      error
+	Attempts int
+}
+
+// NewPipeline creates a pipeline that runs work on numWorkers goroutines
+func NewPipeline[In, Out any](numWorkers int, work func(context.Context, In) (Out, error)) *Pipeline[In, Out] {
+	return &Pipeline[In, Out]{
+		numWorkers: max(numWorkers, 1),
+		work:       work,
+		Retryable:  Retryable,
+	}
+}
+
+// task is a job on its way through the workers
+type task[In any] struct {
+	index   int
+	job     In
+	attempt int
+}
+
+// Run feeds every job from source through the workers and passes each result to sink, failed jobs included
+// A sink error stops the pipeline and gets returned. Jobs cut short by a cancel never reach the sink.
+func (p *Pipeline[In, Out]) Run(ctx context.Context, source iter.Seq[In], sink func(Result[In, Out]) error) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tasks := make(chan task[In], 100)
+	results := make(chan Result[In, Out], 100)
+
+	// pending counts the feeder plus every job without a final result, tasks can only close once it hits zero
+	// because a job waiting out its retry delay still has to go back in
+	var pending sync.WaitGroup
+	pending.Add(1)
+	go func() {
+		defer pending.Done()
+		i := 0
+		for job := range source {
+			pending.Add(1)
+			select {
+			case tasks <- task[In]{index: i, job: job, attempt: 1}:
+			case <-runCtx.Done():
+				pending.Done()
+				return
+			}
+			i++
+		}
+	}()
+	go func() {
+		pending.Wait()
+		close(tasks)
+	}()
+
+	var workers sync.WaitGroup
+	for w := 0; w < p.numWorkers; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for t := range tasks {
+				if runCtx.Err() != nil {
+					pending.Done() // Drain whatever is left without doing the work
+					continue
+				}
+				out, err := p.work(runCtx, t.job)
+				if err != nil && runCtx.Err() != nil {
+					pending.Done() // Most likely failed because of the cancel, not worth reporting
+					continue
+				}
+				if err != nil && t.attempt <= p.Retries && p.Retryable(err) {
+					if p.OnRetry != nil {
+						p.OnRetry(t.job, err, t.attempt)
+					}
+					t.attempt++
+					go func() {
+						select {
+						case <-time.After(p.RetryDelay):
+						case <-runCtx.Done():
+						}
+						tasks <- t
+					}()
+					continue
+				}
+				results <- Result[In, Out]{Index: t.index, Job: t.job, Out: out, Err: err, Attempts: t.attempt}
+				pending.Done()
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	// Results always get drained so the workers never block, the sink just stops being called after an error
+	var sinkErr error
+	emit := func(r Result[In, Out]) {
+		if sinkErr != nil {
+			return
+		}
+		if err := sink(r); err != nil {
+			sinkErr = err
+			cancel()
+		}
+	}
+	buffered := make(map[int]Result[In, Out])
+	next := 0
+	for r := range results {
+		if !p.Ordered {
+			emit(r)
+			continue
+		}
+		buffered[r.Index] = r
+		for {
+			r, ok := buffered[next]
+			if !ok {
+				break
+			}
+			delete(buffered, next)
+			emit(r)
+			next++
+		}
+	}
+	// Only a cancelled run leaves gaps, whatever came after them still goes out in order
+	rest := make([]int, 0, len(buffered))
+	for i := range buffered {
+		rest = append(rest, i)
+	}
+	sort.Ints(rest)
+	for _, i := range rest {
+		emit(buffered[i])
+	}
+
+	if sinkErr != nil {
+		return sinkErr
+	}
+	return ctx.Err()
+}
+
+// Retryable reports whether another attempt could fix a failed fetch, asking again won't make a missing page
+// exist or put an uncached response in the offline cache
+func Retryable(err error) bool {
+	switch ErrorCategory(err) {
+	case CategoryMissing, CategoryNotCached:
+		return false
+	}
+	return true
+}
+
+// batches splits titles into the chunks a single API query takes
+func batches(titles []string, size int) iter.Seq[[]string] {
+	return func(yield func([]string) bool) {
+		for start := 0; start < len(titles); start += size {
+			if !yield(titles[start:min(start+size, len(titles))]) {
+				return
+			}
+		}
+	}
+}
+
+// SignalContext is cancelled on Ctrl-C (or SIGTERM) so a run can stop cleanly, a second Ctrl-C kills it as usual
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop() // Back to the default handler
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,201 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// count yields 0 to n-1, or forever if n is negative
+func count(n int) func(yield func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; n < 0 || i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Early jobs take the longest, so the workers finish them last
+func TestPipelineOrdered(t *testing.T) {
+	const jobs = 20
+	p := NewPipeline(4, func(ctx context.Context, i int) (int, error) {
+		time.Sleep(time.Duration(jobs-i) * time.Millisecond)
+		return i * i, nil
+	})
+
+	for _, ordered := range []bool{false, true} {
+		p.Ordered = ordered
+		var got []int
+		err := p.Run(context.Background(), count(jobs), func(r Result[int, int]) error {
+			if r.Out != r.Job*r.Job || r.Index != r.Job {
+				t.Errorf("job %d at index %d came back with %d", r.Job, r.Index, r.Out)
+			}
+			got = append(got, r.Job)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != jobs {
+			t.Fatalf("ordered=%v: sink got %d results, want %d", ordered, len(got), jobs)
+		}
+		if sorted := slices.IsSorted(got); sorted != ordered {
+			t.Errorf("ordered=%v: results came out as %v", ordered, got)
+		}
+	}
+}
+
+func TestPipelineRetries(t *testing.T) {
+	tests := []struct {
+		category string
+		attempts int
+	}{
+		{CategoryTimeout, 3},
+		{CategoryNetwork, 3},
+		{CategoryRateLimited, 3},
+		{CategoryServer, 3},
+		{CategoryBadResponse, 3},
+		{CategoryMissing, 1},   // Asking again won't create the page
+		{CategoryNotCached, 1}, // Nor put it in the offline cache
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			var calls, retries atomic.Int32
+			p := NewPipeline(2, func(ctx context.Context, job string) (string, error) {
+				calls.Add(1)
+				return "", &FetchError{tt.category, errors.New(job + " failed")}
+			})
+			p.Retries = 2
+			p.OnRetry = func(job string, err error, attempt int) {
+				if want := int(retries.Add(1)); attempt != want {
+					t.Errorf("retry reported as attempt %d, want %d", attempt, want)
+				}
+			}
+
+			var results []Result[string, string]
+			err := p.Run(context.Background(), slices.Values([]string{"Ada Lovelace"}), func(r Result[string, string]) error {
+				results = append(results, r)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("sink got %d results, want 1", len(results))
+			}
+			if r := results[0]; r.Attempts != tt.attempts || ErrorCategory(r.Err) != tt.category {
+				t.Errorf("%d attempts ending in %v, want %d", r.Attempts, r.Err, tt.attempts)
+			}
+			if int(calls.Load()) != tt.attempts || int(retries.Load()) != tt.attempts-1 {
+				t.Errorf("work ran %d times with %d retries", calls.Load(), retries.Load())
+			}
+		})
+	}
+}
+
+// A job that fails once and then works only reaches the sink once, with the good result
+func TestPipelineRetrySucceeds(t *testing.T) {
+	var failed atomic.Bool
+	p := NewPipeline(1, func(ctx context.Context, job int) (int, error) {
+		if !failed.Swap(true) {
+			return 0, &FetchError{CategoryServer, errors.New("503")}
+		}
+		return job + 1, nil
+	})
+	p.Retries = 3
+	p.RetryDelay = time.Millisecond
+
+	var results []Result[int, int]
+	if err := p.Run(context.Background(), count(1), func(r Result[int, int]) error {
+		results = append(results, r)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err != nil || results[0].Out != 1 || results[0].Attempts != 2 {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+// waitForGoroutines polls until the goroutine count is back to n, a finished Run can take a moment to unwind
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines left, started with %d\n%s", runtime.NumGoroutine(), n, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The source never ends, some jobs hang until the cancel and some are waiting to be retried
+	p := NewPipeline(4, func(ctx context.Context, i int) (int, error) {
+		if i%10 == 9 {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		if i%4 == 0 {
+			return 0, &FetchError{CategoryTimeout, errors.New("slow")}
+		}
+		return i, nil
+	})
+	p.Retries = 5
+	p.RetryDelay = time.Hour
+
+	var done int
+	err := p.Run(ctx, count(-1), func(r Result[int, int]) error {
+		if done++; done == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestPipelineSinkError(t *testing.T) {
+	before := runtime.NumGoroutine()
+	const jobs = 1000
+	var calls atomic.Int32
+	p := NewPipeline(2, func(ctx context.Context, i int) (int, error) {
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+		return i, nil
+	})
+	p.Ordered = true
+
+	diskFull := errors.New("no space left on device")
+	var sunk []int
+	err := p.Run(context.Background(), count(jobs), func(r Result[int, int]) error {
+		sunk = append(sunk, r.Job)
+		if len(sunk) == 3 {
+			return diskFull
+		}
+		return nil
+	})
+	if err != diskFull {
+		t.Errorf("err = %v, want %v", err, diskFull)
+	}
+	if !slices.Equal(sunk, []int{0, 1, 2}) {
+		t.Errorf("sink got %v after failing", sunk)
+	}
+	if calls.Load() >= jobs {
+		t.Errorf("all %d jobs ran after the sink failed", jobs)
+	}
+	waitForGoroutines(t, before)
+}
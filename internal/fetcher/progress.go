@@ -0,0 +1,155 @@
+package fetcher
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress tracks a fetch while it runs: how many pages are done, how fast we're going and how long is left
+// On a terminal it redraws a single status line, otherwise (CI, piped to a file) it logs a line every so often
+type Progress struct {
+	total     atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	retried   atomic.Int64
+	stats     *RequestStats // Requests, retries and 429s come straight from the LinkSource
+
+	start    time.Time
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// ProgressSnapshot is a point in time view of a Progress, also used for the final summary report
+type ProgressSnapshot struct {
+	Total          int64   `json:"total"`
+	Done           int64   `json:"done"`
+	Succeeded      int64   `json:"succeeded"`
+	Failed         int64   `json:"failed"`      // Pages that still failed after their last attempt
+	PageRetries    int64   `json:"pageRetries"` // Failed attempts that got the page queued again
+	Requests       int64   `json:"requests"`
+	Retries        int64   `json:"retries"` // HTTP requests retried by the LinkSource
+	RateLimited    int64   `json:"rateLimited"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	RequestsPerSec float64 `json:"requestsPerSec"`
+	PagesPerSec    float64 `json:"pagesPerSec"`
+	ETASeconds     float64 `json:"etaSeconds"`
+}
+
+// NewProgress creates a tracker that reports to stderr, reading request counters from stats
+func NewProgress(stats *RequestStats) *Progress {
+	p := &Progress{
+		stats:    stats,
+		out:      os.Stderr,
+		tty:      isTerminal(os.Stderr),
+		interval: 10 * time.Second,
+	}
+	// A terminal can take redraws a few times a second, logs can't
+	if p.tty {
+		p.interval = 250 * time.Millisecond
+	}
+	return p
+}
+
+// isTerminal reports whether f is a character device (a TTY) rather than a pipe or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// AddTotal adds pages to the amount of work, called when jobs are queued
+func (p *Progress) AddTotal(n int) { p.total.Add(int64(n)) }
+
+// Success records a page fetched without errors
+func (p *Progress) Success() { p.succeeded.Add(1) }
+
+// Failure records a page whose fetch failed for good
+func (p *Progress) Failure() { p.failed.Add(1) }
+
+// Retry records a failed attempt that the page gets another try after
+func (p *Progress) Retry() { p.retried.Add(1) }
+
+// Start begins reporting in the background until Stop is called
+func (p *Progress) Start() {
+	p.start = time.Now()
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background reporting and prints one last status
+func (p *Progress) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+	p.render()
+	if p.tty {
+		fmt.Fprintln(p.out) // Leave the status line behind instead of drawing over it
+	}
+}
+
+// Snapshot returns the current numbers, safe to call at any time
+func (p *Progress) Snapshot() ProgressSnapshot {
+	s := ProgressSnapshot{
+		Total:       p.total.Load(),
+		Succeeded:   p.succeeded.Load(),
+		Failed:      p.failed.Load(),
+		PageRetries: p.retried.Load(),
+		Requests:    p.stats.Requests.Load(),
+		Retries:     p.stats.Retries.Load(),
+		RateLimited: p.stats.RateLimited.Load(),
+	}
+	s.Done = s.Succeeded + s.Failed
+
+	elapsed := time.Since(p.start).Seconds()
+	s.ElapsedSeconds = elapsed
+	if elapsed > 0 {
+		s.RequestsPerSec = float64(s.Requests) / elapsed
+		s.PagesPerSec = float64(s.Done) / elapsed
+	}
+	if s.PagesPerSec > 0 && s.Total > s.Done {
+		s.ETASeconds = float64(s.Total-s.Done) / s.PagesPerSec
+	}
+	return s
+}
+
+func (p *Progress) render() {
+	s := p.Snapshot()
+	eta := (time.Duration(s.ETASeconds) * time.Second).String()
+
+	if p.tty {
+		percent := 0.0
+		if s.Total > 0 {
+			percent = 100 * float64(s.Done) / float64(s.Total)
+		}
+		// \r goes back to the start of the line and \x1b[K clears whatever the last draw left over
+		fmt.Fprintf(p.out, "\r[%d/%d] %.1f%% ok=%d failed=%d requeued=%d %.1f req/s retries=%d 429s=%d ETA %s\x1b[K",
+			s.Done, s.Total, percent, s.Succeeded, s.Failed, s.PageRetries, s.RequestsPerSec, s.Retries, s.RateLimited, eta)
+		return
+	}
+
+	log.Printf("progress done=%d total=%d ok=%d failed=%d requeued=%d req_per_sec=%.1f retries=%d rate_limited=%d eta=%s",
+		s.Done, s.Total, s.Succeeded, s.Failed, s.PageRetries, s.RequestsPerSec, s.Retries, s.RateLimited, eta)
+}
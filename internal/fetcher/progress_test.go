@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressSnapshot(t *testing.T) {
+	stats := &RequestStats{}
+	stats.Requests.Add(40)
+	stats.Retries.Add(3)
+	stats.RateLimited.Add(2)
+
+	p := NewProgress(stats)
+	p.start = time.Now().Add(-10 * time.Second)
+	p.AddTotal(50)
+	p.AddTotal(50) // A second pass adds to the same total
+	for range 18 {
+		p.Success()
+	}
+	p.Failure()
+	p.Failure()
+	p.Retry()
+
+	s := p.Snapshot()
+	if s.Total != 100 || s.Done != 20 || s.Succeeded != 18 || s.Failed != 2 || s.PageRetries != 1 {
+		t.Errorf("counts = %+v", s)
+	}
+	if s.Requests != 40 || s.Retries != 3 || s.RateLimited != 2 {
+		t.Errorf("request stats = %+v", s)
+	}
+	// 20 pages in 10s is 2 a second, the 80 left take another 40s
+	if math.Abs(s.PagesPerSec-2) > 0.1 || math.Abs(s.RequestsPerSec-4) > 0.1 || math.Abs(s.ETASeconds-40) > 2 {
+		t.Errorf("rates = %.2f pages/s, %.2f req/s, ETA %.1fs", s.PagesPerSec, s.RequestsPerSec, s.ETASeconds)
+	}
+
+	// A finished run has no ETA
+	for range 80 {
+		p.Success()
+	}
+	if s := p.Snapshot(); s.Done != s.Total || s.ETASeconds != 0 {
+		t.Errorf("finished run = %+v", s)
+	}
+}
+
+// On a terminal every redraw starts over the same line
+func TestProgressRenderTerminal(t *testing.T) {
+	var out bytes.Buffer
+	p := NewProgress(&RequestStats{})
+	p.out, p.tty, p.interval = &out, true, time.Millisecond
+	p.AddTotal(4)
+	p.Start()
+	p.Success()
+	p.Retry()
+	p.Failure()
+	time.Sleep(10 * time.Millisecond)
+	p.Stop()
+
+	text := out.String()
+	last := text[strings.LastIndex(text, "\r"):]
+	if !strings.HasPrefix(last, "\r[2/4] 50.0% ok=1 failed=1 requeued=1 ") || !strings.HasSuffix(last, "\x1b[K\n") {
+		t.Errorf("last status line = %q", last)
+	}
+	if strings.Count(text, "\n") != 1 {
+		t.Errorf("status lines weren't drawn over each other: %q", text)
+	}
+}
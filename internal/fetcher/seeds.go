@@ -0,0 +1,130 @@
+package fetcher
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+// Seed is one person on the seed list plus the curated groups they belong to (nobel_laureate, olympian, ...)
+type Seed struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// LoadSeeds reads a seed list, the format is picked by extension:
+//   - .csv: a name column and a tags column with tags separated by ';' (header row optional)
+//   - .json: an array of {"name": ..., "tags": [...]}
+//   - anything else: the original plain text format, one name per line and no tags
+//
+// Blank lines and surrounding whitespace are skipped, run cmd/validate to find them
+func LoadSeeds(filename string) ([]Seed, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var seeds []Seed
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		seeds, err = readCSVSeeds(file)
+	case ".json":
+		err = json.NewDecoder(file).Decode(&seeds)
+	default:
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			seeds = append(seeds, Seed{Name: scanner.Text()})
+		}
+		err = scanner.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file %s: %w", filename, err)
+	}
+
+	cleaned := seeds[:0]
+	for _, seed := range seeds {
+		seed.Name = strings.TrimSpace(seed.Name)
+		if seed.Name != "" {
+			cleaned = append(cleaned, seed)
+		}
+	}
+	return cleaned, nil
+}
+
+func readCSVSeeds(file *os.File) ([]Seed, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // The tags column is optional
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds []Seed
+	for i, record := range records {
+		if len(record) == 0 {
+			continue
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			continue // Header row
+		}
+		seed := Seed{Name: record[0]}
+		if len(record) > 1 {
+			for _, tag := range strings.Split(record[1], ";") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					seed.Tags = append(seed.Tags, tag)
+				}
+			}
+		}
+		seeds = append(seeds, seed)
+	}
+	return seeds, nil
+}
+
+// WriteSeeds writes a seed list in the format picked by the file's extension, same rules as LoadSeeds
+func WriteSeeds(filename string, seeds []Seed) error {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		_ = w.Write([]string{"name", "tags"})
+		for _, seed := range seeds {
+			_ = w.Write([]string{seed.Name, strings.Join(seed.Tags, ";")})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		data = []byte(b.String())
+	case ".json":
+		var err error
+		if data, err = json.MarshalIndent(seeds, "", "  "); err != nil {
+			return err
+		}
+	default:
+		var b strings.Builder
+		for _, seed := range seeds {
+			b.WriteString(seed.Name + "\n")
+		}
+		data = []byte(b.String())
+	}
+	return graph.WriteFileAtomic(filename, data)
+}
+
+// SeedTags maps each seed name to its tags, seeds without tags are left out
+func SeedTags(seeds []Seed) map[string][]string {
+	tags := make(map[string][]string)
+	for _, seed := range seeds {
+		if len(seed.Tags) > 0 {
+			tags[seed.Name] = seed.Tags
+		}
+	}
+	return tags
+}
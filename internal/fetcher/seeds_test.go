@@ -0,0 +1,69 @@
+package fetcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSeeds(t *testing.T) {
+	want := []Seed{
+		{Name: "Marie Curie", Tags: []string{"nobel_laureate", "physicist"}},
+		{Name: "Jesse Owens", Tags: []string{"olympian"}},
+		{Name: "Ada Lovelace"},
+	}
+	tests := []struct {
+		file string
+		data string
+		want []Seed
+	}{
+		{"seeds.csv", "name,tags\nMarie Curie,nobel_laureate; physicist\n  Jesse Owens ,olympian;\n\nAda Lovelace\n", want},
+		{"seeds.csv", "Marie Curie,nobel_laureate;physicist\nJesse Owens,olympian\nAda Lovelace,\n", want},
+		{"seeds.json", `[{"name":"Marie Curie","tags":["nobel_laureate","physicist"]},{"name":"Jesse Owens","tags":["olympian"]},{"name":" "},{"name":"Ada Lovelace"}]`, want},
+		{"seeds.txt", "Marie Curie\n\nJesse Owens\r\n Ada Lovelace\n", []Seed{{Name: "Marie Curie"}, {Name: "Jesse Owens"}, {Name: "Ada Lovelace"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			seeds, err := LoadSeeds(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(seeds, tt.want) {
+				t.Errorf("loaded %q, want %q", seeds, tt.want)
+			}
+		})
+	}
+}
+
+// Whatever WriteSeeds writes, LoadSeeds reads back, tags are only lost in the plain text format
+func TestWriteSeeds(t *testing.T) {
+	seeds := []Seed{
+		{Name: "Curie, Marie", Tags: []string{"nobel_laureate", "physicist"}},
+		{Name: "Ada Lovelace"},
+	}
+	for _, name := range []string{"seeds.csv", "seeds.json", "seeds.txt"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := WriteSeeds(path, seeds); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadSeeds(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := seeds
+		if name == "seeds.txt" {
+			want = []Seed{{Name: "Curie, Marie"}, {Name: "Ada Lovelace"}}
+		}
+		if !reflect.DeepEqual(loaded, want) {
+			t.Errorf("%s: loaded %q, want %q", name, loaded, want)
+		}
+	}
+	if tags := SeedTags(seeds); !reflect.DeepEqual(tags, map[string][]string{"Curie, Marie": {"nobel_laureate", "physicist"}}) {
+		t.Errorf("tags = %v", tags)
+	}
+}
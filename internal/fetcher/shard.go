@@ -0,0 +1,20 @@
+package fetcher
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// ShardOf picks the shard a seed belongs to out of count, hashing the name so every machine agrees
+// without talking to the others and adding a seed doesn't reshuffle the rest
+func ShardOf(name string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % uint32(count))
+}
+
+// PartialFileName names the partial graph a shard writes, e.g. partial.en.2-of-8.json
+// It doesn't start with "graph" so the server never mistakes it for a graph to serve
+func PartialFileName(lang string, index, count int) string {
+	return fmt.Sprintf("partial.%s.%d-of-%d.json", lang, index, count)
+}
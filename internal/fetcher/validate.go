@@ -0,0 +1,223 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Seed list problem categories
+const (
+	IssueBlank          = "blank_line"
+	IssueWhitespace     = "whitespace"     // Leading/trailing spaces, the title wouldn't match link titles
+	IssueDuplicate      = "duplicate"      // Same title twice (after trimming, normalizing and following redirects)
+	IssueNormalized     = "normalized"     // The API normalizes the title (underscores, first letter case)
+	IssueRedirect       = "redirect"       // Title redirects elsewhere, links point at the target so the seed never matches
+	IssueMissing        = "missing"        // No such page
+	IssueInvalid        = "invalid"        // Not a valid title at all
+	IssueDisambiguation = "disambiguation" // Disambiguation page, not a person
+)
+
+// TitleStatus is what the API says about one title
+type TitleStatus struct {
+	Title          string // Title as asked
+	Normalized     string // Set if the API normalized it
+	RedirectTo     string // Set if the (normalized) title is a redirect
+	Missing        bool
+	Invalid        bool
+	Disambiguation bool
+}
+
+// Resolved is the article the title ends up at after normalizing and following redirects
+func (t TitleStatus) Resolved() string {
+	switch {
+	case t.RedirectTo != "":
+		return t.RedirectTo
+	case t.Normalized != "":
+		return t.Normalized
+	}
+	return t.Title
+}
+
+// SeedIssue is one problem found in the seed list
+type SeedIssue struct {
+	Line     int    `json:"line"` // 1-based line number in the seed file, or entry number for a structured one (see SeedReport.Unit)
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// SeedReport is the result of validating a seed list
+type SeedReport struct {
+	Lines      int               `json:"lines"`
+	Unit       string            `json:"unit"` // What Lines and each issue's Line count, "line" or "entry"
+	Issues     []SeedIssue       `json:"issues"`
+	ByCategory map[string]int    `json:"byCategory"`
+	Fixed      []string          `json:"-"` // Corrected seed list: trimmed, deduplicated, redirects resolved, bad titles dropped
+	Resolved   map[string]string `json:"-"` // Trimmed title -> its entry in Fixed, duplicates map to the same entry
+}
+
+// CheckTitles asks the API about every title in bulk: missing, invalid, redirect, disambiguation
+// Batches of 50 run concurrently through a Pipeline
+func (s *LinkSource) CheckTitles(ctx context.Context, titles []string) (map[string]TitleStatus, error) {
+	statuses := make(map[string]TitleStatus, len(titles))
+
+	p := NewPipeline(batchWorkers, s.checkBatch)
+	err := p.Run(ctx, batches(titles, titlesPerRequest), func(r Result[[]string, map[string]TitleStatus]) error {
+		if r.Err != nil {
+			return r.Err
+		}
+		for title, status := range r.Out {
+			statuses[title] = status
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// checkBatch runs CheckTitles' query for up to 50 titles
+func (s *LinkSource) checkBatch(ctx context.Context, batch []string) (map[string]TitleStatus, error) {
+	statuses := make(map[string]TitleStatus, len(batch))
+
+	// `redirects=1` = resolve redirects and tell us about them
+	// `ppprop=disambiguation` = only the page prop that flags disambiguation pages
+	requestURL := fmt.Sprintf("%s?action=query&prop=pageprops&ppprop=disambiguation&redirects=1&format=json&titles=%s",
+		s.endpoint, url.QueryEscape(strings.Join(batch, "|")))
+
+	var result models.WikiTitleCheckResponse
+	if err := s.getJSON(ctx, requestURL, &result); err != nil {
+		return nil, err
+	}
+
+	normalized := make(map[string]string)
+	for _, n := range result.Query.Normalized {
+		normalized[n.From] = n.To
+	}
+	redirects := make(map[string]string)
+	for _, r := range result.Query.Redirects {
+		redirects[r.From] = r.To
+	}
+	pages := make(map[string]TitleStatus)
+	for _, page := range result.Query.Pages {
+		pages[page.Title] = TitleStatus{
+			Missing:        page.Missing != nil,
+			Invalid:        page.Invalid != nil,
+			Disambiguation: page.Pageprops.Disambiguation != nil,
+		}
+	}
+
+	for _, title := range batch {
+		status := TitleStatus{Title: title}
+		current := title
+		if n, ok := normalized[current]; ok {
+			status.Normalized = n
+			current = n
+		}
+		if r, ok := redirects[current]; ok {
+			status.RedirectTo = r
+			current = r
+		}
+		page, ok := pages[current]
+		status.Missing = page.Missing
+		status.Invalid = page.Invalid || (!ok && status.RedirectTo == "")
+		status.Disambiguation = page.Disambiguation
+		statuses[title] = status
+	}
+
+	return statuses, nil
+}
+
+// ValidateSeeds lints a plain text seed list line by line against the source wiki
+// Local checks (blank, whitespace, duplicates) run first, then every remaining title is checked with the API
+func ValidateSeeds(ctx context.Context, lines []string, source *LinkSource) (*SeedReport, error) {
+	return validateSeeds(ctx, lines, "line", source)
+}
+
+// ValidateEntries is ValidateSeeds for the names of a structured seed file (CSV, JSON), issues point at entries
+// since an entry's line in the file isn't known
+func ValidateEntries(ctx context.Context, names []string, source *LinkSource) (*SeedReport, error) {
+	return validateSeeds(ctx, names, "entry", source)
+}
+
+func validateSeeds(ctx context.Context, lines []string, unit string, source *LinkSource) (*SeedReport, error) {
+	report := &SeedReport{Lines: len(lines), Unit: unit, Issues: []SeedIssue{}, ByCategory: make(map[string]int), Resolved: make(map[string]string)}
+	add := func(line int, title, category, detail string) {
+		report.Issues = append(report.Issues, SeedIssue{Line: line, Title: title, Category: category, Detail: detail})
+		report.ByCategory[category]++
+	}
+
+	// Local checks, remember the first line each trimmed title showed up on
+	firstLine := make(map[string]int)
+	var titles []string
+	for i, raw := range lines {
+		lineNo := i + 1
+		title := strings.TrimSpace(raw)
+		if title == "" {
+			add(lineNo, raw, IssueBlank, "")
+			continue
+		}
+		if title != raw {
+			add(lineNo, raw, IssueWhitespace, fmt.Sprintf("trimmed to %q", title))
+		}
+		if first, ok := firstLine[title]; ok {
+			add(lineNo, title, IssueDuplicate, fmt.Sprintf("same as %s %d", unit, first))
+			continue
+		}
+		firstLine[title] = lineNo
+		titles = append(titles, title)
+	}
+
+	statuses, err := source.CheckTitles(ctx, titles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remote checks, two seeds can still end up at the same article through redirects
+	resolvedLine := make(map[string]int)
+	for _, title := range titles {
+		lineNo := firstLine[title]
+		status := statuses[title]
+		switch {
+		case status.Invalid:
+			add(lineNo, title, IssueInvalid, "")
+			continue
+		case status.Missing:
+			add(lineNo, title, IssueMissing, "")
+			continue
+		case status.Disambiguation:
+			add(lineNo, title, IssueDisambiguation, "")
+			continue
+		}
+
+		if status.RedirectTo != "" {
+			add(lineNo, title, IssueRedirect, fmt.Sprintf("redirects to %q", status.RedirectTo))
+		} else if status.Normalized != "" {
+			add(lineNo, title, IssueNormalized, fmt.Sprintf("normalized to %q", status.Normalized))
+		}
+
+		resolved := status.Resolved()
+		report.Resolved[title] = resolved
+		if first, ok := resolvedLine[resolved]; ok {
+			add(lineNo, title, IssueDuplicate, fmt.Sprintf("%q is also %s %d", resolved, unit, first))
+			continue
+		}
+		resolvedLine[resolved] = lineNo
+		report.Fixed = append(report.Fixed, resolved)
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
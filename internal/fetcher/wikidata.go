@@ -0,0 +1,265 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+const wikidataEndpoint = "https://www.wikidata.org/w/api.php"
+
+// Shared relations link everyone pointing at the same item, which blows up for huge groups
+// ("member of: American Academy of Arts and Sciences"), so groups bigger than this are skipped
+const DefaultMaxGroupSize = 50
+
+// Wikidata properties that point straight at another person
+var directProps = map[string]string{
+	"P26":   models.RelationSpouse,
+	"P40":   models.RelationChild,
+	"P22":   models.RelationParent, // father
+	"P25":   models.RelationParent, // mother
+	"P3373": models.RelationSibling,
+}
+
+// Wikidata properties where two people are related by pointing at the same item
+var sharedProps = map[string]string{
+	"P108": models.RelationEmployer,
+	"P166": models.RelationAward,
+	"P463": models.RelationMemberOf,
+	"P54":  models.RelationTeam,
+}
+
+// Reverse of each direct relation, so a child edge also gives the parent edge back
+var reverseRelation = map[string]string{
+	models.RelationSpouse:  models.RelationSpouse,
+	models.RelationChild:   models.RelationParent,
+	models.RelationParent:  models.RelationChild,
+	models.RelationSibling: models.RelationSibling,
+}
+
+// WikidataSource reads claims from Wikidata (or any wbgetentities compatible endpoint, like a local fixture server)
+type WikidataSource struct {
+	apiClient
+	MaxGroupSize int
+}
+
+// NewWikidataSource creates a Wikidata source, empty endpoint means www.wikidata.org
+func NewWikidataSource(endpoint string) *WikidataSource {
+	if endpoint == "" {
+		endpoint = wikidataEndpoint
+	}
+	return &WikidataSource{apiClient: apiClient{endpoint: endpoint}, MaxGroupSize: DefaultMaxGroupSize}
+}
+
+// claimValue is one relation a person has: the property's relation type, the item it points at and its year if any
+type claimValue struct {
+	relation string
+	item     string
+	year     string
+}
+
+// FetchRelations builds typed edges between seed people from their Wikidata claims
+// qids maps seed name -> QID (from the nodes metadata), edges only ever connect two seeds
+func (s *WikidataSource) FetchRelations(ctx context.Context, qids map[string]string) (models.TypedGraph, error) {
+	nameByQID := make(map[string]string, len(qids))
+	ids := make([]string, 0, len(qids))
+	for name, qid := range qids {
+		if qid == "" {
+			continue
+		}
+		nameByQID[qid] = name
+		ids = append(ids, qid)
+	}
+	sort.Strings(ids)
+
+	claims, err := s.fetchClaims(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := make(models.TypedGraph)
+	seen := make(map[[3]string]bool) // from, to, relation
+	addEdge := func(from, to, relation, via string) {
+		key := [3]string{from, to, relation}
+		if from == to || seen[key] {
+			return
+		}
+		seen[key] = true
+		typed[from] = append(typed[from], models.TypedEdge{To: to, Relation: relation, Via: via})
+	}
+
+	groups := make(map[string][]string) // relation|item|year -> names
+	for qid, values := range claims {
+		from := nameByQID[qid]
+		for _, v := range values {
+			if _, ok := sharedProps[v.relation]; ok {
+				key := sharedProps[v.relation] + "|" + v.item + "|" + v.year
+				groups[key] = append(groups[key], from)
+				continue
+			}
+			// Direct relations only count when the other person is a seed too
+			to, ok := nameByQID[v.item]
+			if !ok {
+				continue
+			}
+			relation := directProps[v.relation]
+			addEdge(from, to, relation, "")
+			addEdge(to, from, reverseRelation[relation], "")
+		}
+	}
+
+	for key, names := range groups {
+		if len(names) < 2 || len(names) > s.MaxGroupSize {
+			delete(groups, key)
+		}
+	}
+
+	// Labels for the shared items so paths can say "share an employer (Princeton University)" instead of a QID
+	viaIDs := make(map[string]bool)
+	for key := range groups {
+		viaIDs[strings.SplitN(key, "|", 3)[1]] = true
+	}
+	labels, err := s.fetchLabels(ctx, viaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, names := range groups {
+		parts := strings.SplitN(key, "|", 3)
+		relation, via := parts[0], parts[1]
+		for _, a := range names {
+			for _, b := range names {
+				addEdge(a, b, relation, via)
+			}
+		}
+	}
+	for from, edges := range typed {
+		for i := range edges {
+			if edges[i].Via != "" {
+				typed[from][i].ViaLabel = labels[edges[i].Via]
+			}
+		}
+	}
+
+	// Stable order so the relations file diffs nicely
+	for from := range typed {
+		sort.Slice(typed[from], func(i, j int) bool {
+			a, b := typed[from][i], typed[from][j]
+			if a.To != b.To {
+				return a.To < b.To
+			}
+			return a.Relation < b.Relation
+		})
+	}
+	return typed, nil
+}
+
+// fetchClaims gets the relation claims of every QID, 50 entities per request
+func (s *WikidataSource) fetchClaims(ctx context.Context, ids []string) (map[string][]claimValue, error) {
+	claims := make(map[string][]claimValue, len(ids))
+
+	for start := 0; start < len(ids); start += titlesPerRequest {
+		end := min(start+titlesPerRequest, len(ids))
+		requestURL := fmt.Sprintf("%s?action=wbgetentities&props=claims&format=json&ids=%s",
+			s.endpoint, url.QueryEscape(strings.Join(ids[start:end], "|")))
+
+		var result models.WikidataEntitiesResponse
+		if err := s.getJSON(ctx, requestURL, &result); err != nil {
+			return nil, err
+		}
+		if result.Error != nil {
+			return nil, &FetchError{CategoryBadResponse, fmt.Errorf("wikidata API error %s: %s", result.Error.Code, result.Error.Info)}
+		}
+
+		for qid, entity := range result.Entities {
+			if entity.Missing != nil {
+				continue
+			}
+			for prop, statements := range entity.Claims {
+				if _, direct := directProps[prop]; !direct {
+					if _, shared := sharedProps[prop]; !shared {
+						continue
+					}
+				}
+				for _, st := range statements {
+					item, ok := entityID(st.Mainsnak)
+					if !ok {
+						continue
+					}
+					v := claimValue{relation: prop, item: item}
+					// Award co-recipients means the same award in the same year
+					if prop == "P166" {
+						v.year = qualifierYear(st.Qualifiers["P585"])
+					}
+					claims[qid] = append(claims[qid], v)
+				}
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+// fetchLabels gets the English label of each item, 50 per request
+func (s *WikidataSource) fetchLabels(ctx context.Context, ids map[string]bool) (map[string]string, error) {
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	labels := make(map[string]string, len(sorted))
+	for start := 0; start < len(sorted); start += titlesPerRequest {
+		end := min(start+titlesPerRequest, len(sorted))
+		requestURL := fmt.Sprintf("%s?action=wbgetentities&props=labels&languages=en&format=json&ids=%s",
+			s.endpoint, url.QueryEscape(strings.Join(sorted[start:end], "|")))
+
+		var result models.WikidataEntitiesResponse
+		if err := s.getJSON(ctx, requestURL, &result); err != nil {
+			return nil, err
+		}
+		for id, entity := range result.Entities {
+			if label, ok := entity.Labels["en"]; ok {
+				labels[id] = label.Value
+			}
+		}
+	}
+	return labels, nil
+}
+
+// entityID pulls the item ID out of a snak pointing at another item
+func entityID(snak models.WikidataSnak) (string, bool) {
+	if snak.Snaktype != "value" || snak.Datavalue.Type != "wikibase-entityid" {
+		return "", false
+	}
+	var value struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(snak.Datavalue.Value, &value); err != nil || value.ID == "" {
+		return "", false
+	}
+	return value.ID, true
+}
+
+// qualifierYear returns the year of a "point in time" qualifier, e.g. "+1921-00-00T00:00:00Z" -> "1921"
+func qualifierYear(snaks []models.WikidataSnak) string {
+	for _, snak := range snaks {
+		if snak.Snaktype != "value" || snak.Datavalue.Type != "time" {
+			continue
+		}
+		var value struct {
+			Time string `json:"time"`
+		}
+		if err := json.Unmarshal(snak.Datavalue.Value, &value); err != nil {
+			continue
+		}
+		year, _, _ := strings.Cut(strings.TrimPrefix(value.Time, "+"), "-")
+		return year
+	}
+	return ""
+}
@@ -0,0 +1,253 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// fixtureEntity is what the fixture server knows about one Wikidata item
+type fixtureEntity struct {
+	claims map[string][]any
+	label  string
+}
+
+// itemClaim is a statement pointing at another item, year adds a point in time qualifier
+func itemClaim(id, year string) any {
+	claim := map[string]any{"mainsnak": map[string]any{
+		"snaktype":  "value",
+		"datavalue": map[string]any{"type": "wikibase-entityid", "value": map[string]any{"id": id}},
+	}}
+	if year != "" {
+		claim["qualifiers"] = map[string]any{"P585": []any{map[string]any{
+			"snaktype":  "value",
+			"datavalue": map[string]any{"type": "time", "value": map[string]any{"time": "+" + year + "-00-00T00:00:00Z"}},
+		}}}
+	}
+	return claim
+}
+
+// wikidataFixture serves wbgetentities for the given items the way Wikidata does, unknown IDs come back missing
+// The returned func lists the requests it got so far as "props ids"
+func wikidataFixture(t *testing.T, entities map[string]fixtureEntity) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("action") != "wbgetentities" {
+			http.Error(w, "unexpected action "+q.Get("action"), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, q.Get("props")+" "+q.Get("ids"))
+		mu.Unlock()
+
+		out := make(map[string]any)
+		for _, id := range strings.Split(q.Get("ids"), "|") {
+			e, ok := entities[id]
+			if !ok {
+				out[id] = map[string]any{"id": id, "missing": ""}
+				continue
+			}
+			entity := map[string]any{"id": id}
+			switch q.Get("props") {
+			case "claims":
+				entity["claims"] = e.claims
+			case "labels":
+				entity["labels"] = map[string]any{"en": map[string]any{"value": e.label}}
+			}
+			out[id] = entity
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]any{"entities": out})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+// curieEntities is the Curie family: two spouses who share an award in 1903 and an employer, their daughters,
+// one of whom got the same award in another year, and a spouse who isn't a seed
+var curieEntities = map[string]fixtureEntity{
+	"Q1": {claims: map[string][]any{ // Marie
+		"P26":  {itemClaim("Q2", ""), itemClaim("Q9", "")},
+		"P40":  {itemClaim("Q3", "")},
+		"P166": {itemClaim("Q100", "1903")},
+		"P108": {itemClaim("Q200", "")},
+		"P569": {itemClaim("Q300", "")}, // Not a relation we read
+	}},
+	"Q2": {claims: map[string][]any{ // Pierre
+		"P26":  {itemClaim("Q1", "")},
+		"P166": {itemClaim("Q100", "1903")},
+		"P108": {itemClaim("Q200", "")},
+	}},
+	"Q3": {claims: map[string][]any{ // Irene
+		"P22":   {itemClaim("Q2", "")},
+		"P25":   {itemClaim("Q1", "")},
+		"P3373": {itemClaim("Q4", "")},
+		"P166":  {itemClaim("Q100", "1935")},
+	}},
+	"Q4": {claims: map[string][]any{ // Eve
+		"P3373": {itemClaim("Q3", "")},
+		"P108":  {itemClaim("Q200", "")},
+	}},
+	"Q100": {label: "Nobel Prize in Physics"},
+	"Q200": {label: "University of Paris"},
+}
+
+var curieSeeds = map[string]string{
+	"Marie Curie":        "Q1",
+	"Pierre Curie":       "Q2",
+	"Irene Joliot-Curie": "Q3",
+	"Eve Curie":          "Q4",
+	"No Item":            "",
+}
+
+// flatten lists a typed graph as "from -relation(via)-> to" lines, sorted
+func flatten(typed models.TypedGraph) []string {
+	var lines []string
+	for from, edges := range typed {
+		for _, e := range edges {
+			relation := e.Relation
+			if e.Via != "" {
+				relation += fmt.Sprintf("(%s %s)", e.Via, e.ViaLabel)
+			}
+			lines = append(lines, fmt.Sprintf("%s -%s-> %s", from, relation, e.To))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestFetchRelations(t *testing.T) {
+	employer := []string{
+		"Eve Curie -employer(Q200 University of Paris)-> Marie Curie",
+		"Eve Curie -employer(Q200 University of Paris)-> Pierre Curie",
+		"Marie Curie -employer(Q200 University of Paris)-> Eve Curie",
+		"Marie Curie -employer(Q200 University of Paris)-> Pierre Curie",
+		"Pierre Curie -employer(Q200 University of Paris)-> Eve Curie",
+		"Pierre Curie -employer(Q200 University of Paris)-> Marie Curie",
+	}
+	// Spouse both ways from either claim, child and parent from P40, P22 and P25, and the 1903 award
+	// but not Irene's 1935 one or Marie's spouse who isn't a seed
+	others := []string{
+		"Eve Curie -sibling-> Irene Joliot-Curie",
+		"Irene Joliot-Curie -parent-> Marie Curie",
+		"Irene Joliot-Curie -parent-> Pierre Curie",
+		"Irene Joliot-Curie -sibling-> Eve Curie",
+		"Marie Curie -award(Q100 Nobel Prize in Physics)-> Pierre Curie",
+		"Marie Curie -child-> Irene Joliot-Curie",
+		"Marie Curie -spouse-> Pierre Curie",
+		"Pierre Curie -award(Q100 Nobel Prize in Physics)-> Marie Curie",
+		"Pierre Curie -child-> Irene Joliot-Curie",
+		"Pierre Curie -spouse-> Marie Curie",
+	}
+
+	tests := []struct {
+		name         string
+		maxGroupSize int
+		want         []string
+	}{
+		{"default group size", DefaultMaxGroupSize, append(append([]string{}, employer...), others...)},
+		// The employer has three seeds, so it's skipped, the award's group of two stays
+		{"groups capped at two", 2, others},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := wikidataFixture(t, curieEntities)
+			source := NewWikidataSource(srv.URL)
+			source.MaxGroupSize = tt.maxGroupSize
+
+			typed, err := source.FetchRelations(context.Background(), curieSeeds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append([]string{}, tt.want...)
+			sort.Strings(want)
+			if got := flatten(typed); !reflect.DeepEqual(got, want) {
+				t.Errorf("relations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+
+			// Each person's edges come sorted by target then relation
+			for from, edges := range typed {
+				sorted := sort.SliceIsSorted(edges, func(i, j int) bool {
+					if edges[i].To != edges[j].To {
+						return edges[i].To < edges[j].To
+					}
+					return edges[i].Relation < edges[j].Relation
+				})
+				if !sorted {
+					t.Errorf("edges of %s aren't sorted: %v", from, edges)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchRelationsBatches(t *testing.T) {
+	entities := make(map[string]fixtureEntity)
+	seeds := make(map[string]string)
+	for i := range titlesPerRequest + 1 {
+		id := fmt.Sprintf("Q%d", i+1)
+		entities[id] = fixtureEntity{claims: map[string][]any{"P108": {itemClaim("Q999", "")}}}
+		seeds[fmt.Sprintf("Person %d", i+1)] = id
+	}
+	entities["Q999"] = fixtureEntity{label: "CERN"}
+	srv, requests := wikidataFixture(t, entities)
+	source := NewWikidataSource(srv.URL)
+	source.MaxGroupSize = titlesPerRequest + 1
+
+	typed, err := source.FetchRelations(context.Background(), seeds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 51 claims lookups take two requests, the one label one more
+	if got := requests(); len(got) != 3 {
+		t.Errorf("made %d requests, want 3: %v", len(got), got)
+	}
+	if got := len(typed["Person 1"]); got != titlesPerRequest {
+		t.Errorf("Person 1 has %d employer edges, want %d", got, titlesPerRequest)
+	}
+}
+
+func TestFetchRelationsAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"error":{"code":"no-such-entity","info":"Could not find an entity"}}`)
+	}))
+	defer srv.Close()
+
+	_, err := NewWikidataSource(srv.URL).FetchRelations(context.Background(), map[string]string{"Marie Curie": "Q1"})
+	if err == nil || ErrorCategory(err) != CategoryBadResponse {
+		t.Errorf("got %v, want a %s error", err, CategoryBadResponse)
+	}
+}
+
+func TestFetchHumans(t *testing.T) {
+	srv, _ := wikidataFixture(t, map[string]fixtureEntity{
+		"Q1":   {claims: map[string][]any{"P31": {itemClaim("Q5", "")}}},
+		"Q2":   {claims: map[string][]any{"P31": {itemClaim("Q3918", ""), itemClaim("Q5", "")}}},
+		"Q200": {claims: map[string][]any{"P31": {itemClaim("Q3918", "")}}}, // A university
+	})
+
+	humans, err := NewWikidataSource(srv.URL).FetchHumans(context.Background(), []string{"Q1", "Q2", "Q200", "Q404"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"Q1": true, "Q2": true}; !reflect.DeepEqual(humans, want) {
+		t.Errorf("humans = %v, want %v", humans, want)
+	}
+}
@@ -0,0 +1,392 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Longest snippet we keep per link, anything longer gets cut with an ellipsis
+const maxSnippetRunes = 300
+
+// FetchWikitext gets the raw wikitext source of an article (redirects are followed)
+func (s *LinkSource) FetchWikitext(ctx context.Context, pageTitle string) (string, error) {
+	requestURL := fmt.Sprintf("%s?action=parse&prop=wikitext&format=json&formatversion=2&redirects=1&page=%s",
+		s.endpoint, url.QueryEscape(pageTitle))
+
+	var result models.WikiParseResponse
+	if err := s.getJSON(ctx, requestURL, &result); err != nil {
+		return "", err
+	}
+	if result.Error != nil {
+		if result.Error.Code == "missingtitle" {
+			return "", &FetchError{CategoryMissing, fmt.Errorf("page %q does not exist", pageTitle)}
+		}
+		return "", &FetchError{CategoryBadResponse, fmt.Errorf("parse API error %s: %s", result.Error.Code, result.Error.Info)}
+	}
+	return result.Parse.Wikitext, nil
+}
+
+// ExtractLinkContexts finds the [[wikilinks]] in an article's wikitext and records, for the first
+// occurrence of each target, the section it's in, the sentence around it and whether it sits in
+// prose, an infobox or some other template. Keys are normalized link targets.
+//
+// Links that prop=links reports but that never show up here come from transcluded templates
+// (navboxes and friends), see ContextFor.
+func ExtractLinkContexts(wikitext string) map[string]models.LinkContext {
+	contexts := make(map[string]models.LinkContext)
+	section := ""
+	var templates []string // Names of the templates we're currently inside, outermost first
+	lineStart := 0
+	unclosed := unclosedTemplates(wikitext)
+
+	for i := 0; i < len(wikitext); {
+		// Section headings only count at the start of a line and outside templates
+		if i == lineStart && len(templates) == 0 {
+			if heading, ok := parseHeading(lineAt(wikitext, lineStart)); ok {
+				section = heading
+			}
+		}
+
+		rest := wikitext[i:]
+		switch {
+		case rest[0] == '\n':
+			i++
+			lineStart = i
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				return contexts
+			}
+			// A comment over several lines leaves us on a later line, it starts where the comment ends
+			if strings.Contains(rest[:end], "\n") {
+				lineStart = i + end + len("-->")
+			}
+			i += end + len("-->")
+		case strings.HasPrefix(rest, "{{") && unclosed[i]:
+			i += 2 // Shows up as plain text, the links after it are still in the prose
+		case strings.HasPrefix(rest, "{{"):
+			templates = append(templates, templateName(rest[2:]))
+			i += 2
+		case strings.HasPrefix(rest, "}}"):
+			if len(templates) > 0 {
+				templates = templates[:len(templates)-1]
+			}
+			i += 2
+		case strings.HasPrefix(rest, "[["):
+			end := strings.Index(rest[2:], "]]")
+			inner := ""
+			if end >= 0 {
+				inner = rest[2 : 2+end]
+			}
+			// File captions can hold links of their own, so step inside and keep scanning
+			if end < 0 || strings.Contains(inner, "[[") {
+				i += 2
+				continue
+			}
+
+			target := linkTarget(inner)
+			if _, seen := contexts[target]; target != "" && !seen {
+				location := locationFor(templates)
+				line := lineAt(wikitext, lineStart)
+				ctx := models.LinkContext{Section: section, Location: location}
+				if location == models.LocationInfobox {
+					ctx.Snippet = infoboxSnippet(line, i-lineStart)
+				} else {
+					ctx.Snippet = sentenceAround(line, i-lineStart, i-lineStart+2+end+2)
+				}
+				contexts[target] = ctx
+			}
+			i += 2 + end + 2
+		default:
+			i++
+		}
+	}
+
+	return contexts
+}
+
+// unclosedTemplates finds the "{{" that never get closed, MediaWiki shows those as text instead of swallowing
+// the rest of the article
+func unclosedTemplates(wikitext string) map[int]bool {
+	var open []int
+	for i := 0; i < len(wikitext); {
+		rest := wikitext[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end + len("-->")
+		case strings.HasPrefix(rest, "{{"):
+			open = append(open, i)
+			i += 2
+		case strings.HasPrefix(rest, "}}"):
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			i += 2
+		default:
+			i++
+		}
+	}
+	unclosed := make(map[int]bool, len(open))
+	for _, at := range open {
+		unclosed[at] = true
+	}
+	return unclosed
+}
+
+// ContextFor looks up a link's context, links missing from the wikitext were pulled in by a template
+func ContextFor(contexts map[string]models.LinkContext, link string) models.LinkContext {
+	if ctx, ok := contexts[link]; ok {
+		return ctx
+	}
+	return models.LinkContext{Location: models.LocationTemplate}
+}
+
+// locationFor classifies a link by the templates it's nested in
+func locationFor(templates []string) string {
+	if len(templates) == 0 {
+		return models.LocationBody
+	}
+	if strings.HasPrefix(strings.ToLower(templates[0]), "infobox") {
+		return models.LocationInfobox
+	}
+	return models.LocationTemplate
+}
+
+// lineAt returns the line starting at start, without the newline
+func lineAt(text string, start int) string {
+	if end := strings.IndexByte(text[start:], '\n'); end >= 0 {
+		return text[start : start+end]
+	}
+	return text[start:]
+}
+
+// parseHeading turns "== Early life ==" into "Early life"
+func parseHeading(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) < 4 || !strings.HasPrefix(line, "==") || !strings.HasSuffix(line, "==") {
+		return "", false
+	}
+	heading := strings.TrimSpace(strings.Trim(line, "="))
+	return plainText(heading), heading != ""
+}
+
+// templateName returns the name of a template from the text right after its "{{"
+func templateName(text string) string {
+	end := strings.IndexAny(text, "|}\n")
+	if end < 0 {
+		end = len(text)
+	}
+	return strings.TrimSpace(text[:end])
+}
+
+// Namespaces whose links aren't links to articles
+var skipNamespaces = map[string]bool{
+	"file": true, "image": true, "media": true, "category": true, "template": true, "help": true,
+	"portal": true, "wikipedia": true, "wp": true, "user": true, "talk": true, "special": true,
+	"draft": true, "module": true, "wikt": true, "wiktionary": true, "commons": true, "s": true, "q": true,
+}
+
+// Wikipedia language codes, the prefixes of interlanguage links like [[de:Albert Einstein]]
+var interlanguage = make(map[string]bool)
+
+func init() {
+	for _, lang := range strings.Fields(`
+		aa ab ace ady af ak als alt am ami an ang anp ar arc ary arz as ast atj av avk awa ay az azb
+		ba ban bar bat-smg bbc bcl be be-tarask be-x-old bew bg bh bi bjn blk bm bn bo bpy br bs bug bxr
+		ca cbk-zam cdo ce ceb ch cho chr chy ckb co cr crh cs csb cu cv cy da dag de dga din diq dsb dtp dty dv dz
+		ee el eml en eo es et eu ext fa fat ff fi fiu-vro fj fo fon fr frp frr fur fy
+		ga gag gan gcr gd gl glk gn gom gor got gpe gu guc gur guw gv ha hak haw he hi hif ho hr hsb ht hu hy hyw hz
+		ia iba id ie ig igl ii ik ilo inh io is it iu ja jam jbo jv
+		ka kaa kab kbd kbp kcg kg kge ki kj kk kl km kn knc ko koi kr krc ks ksh ku kus kv kw ky
+		la lad lb lbe lez lfn lg li lij lld lmo ln lo lrc lt ltg lv lzh
+		mad mai map-bms mdf mg mh mhr mi min mk ml mn mni mnw mos mr mrj ms mt mus mwl my myv mzn
+		na nah nan nap nds nds-nl ne new ng nia nl nn no nov nqo nr nrm nso nup nv ny
+		oc olo om or os pa pag pam pap pcd pcm pdc pfl pi pih pl pms pnb pnt ps pt pwn qu
+		rm rmy rn ro roa-rup roa-tara rsk ru rue rw sa sah sat sc scn sco sd se sg sh shi shn si simple sk skr sl sm smn
+		sn so sq sr srn ss st stq su sv sw szl szy ta tay tcy tdd te tet tg th ti tig tk tl tly tn to tpi tr trv ts tt
+		tum tw ty tyv udm ug uk ur uz ve vec vep vi vls vo wa war wo wuu xal xh xmf yi yo yue
+		za zea zgh zh zh-classical zh-min-nan zh-yue zu`) {
+		interlanguage[lang] = true
+	}
+}
+
+// linkTarget turns the inside of a [[...]] into the normalized title it links to
+// It comes back empty for links outside the main namespace and links to other language editions
+func linkTarget(inner string) string {
+	target, _, _ := strings.Cut(inner, "|")
+	target = strings.TrimPrefix(strings.TrimSpace(target), ":")
+
+	if prefix, _, ok := strings.Cut(target, ":"); ok {
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if skipNamespaces[prefix] || interlanguage[prefix] {
+			return ""
+		}
+	}
+
+	target, _, _ = strings.Cut(target, "#")
+	return normalizeTitle(target)
+}
+
+// normalizeTitle matches how the API normalizes titles: underscores become spaces, first letter upper case
+func normalizeTitle(title string) string {
+	title = strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " ")
+	if title == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(r)) + title[size:]
+}
+
+// infoboxSnippet turns an infobox row like "| spouse = [[Mileva Marić]]" into "spouse: Mileva Marić"
+// pos is where the link starts, in a row like "{{marriage|[[Mileva Marić]]|1903}}{{marriage|...}}" the snippet
+// is the part with that link
+func infoboxSnippet(line string, pos int) string {
+	line = line[:pos] + "\x00" + line[pos:]
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "|"))
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return truncate(unmark(plainText(line)))
+	}
+	return truncate(unmark(strings.TrimSpace(key) + ": " + plainText(value)))
+}
+
+// sentenceAround returns the sentence of a paragraph that contains the link from start to end
+func sentenceAround(paragraph string, start, end int) string {
+	// Mark where the link starts and ends so both survive the markup stripping, whatever the link's text turns into
+	end = min(end, len(paragraph))
+	plain := plainText(paragraph[:start] + "\x00" + paragraph[start:end] + "\x01" + paragraph[end:])
+	before, rest, _ := strings.Cut(plain, "\x00")
+	link, after, _ := strings.Cut(rest, "\x01")
+
+	// Walk back to the end of the previous sentence
+	if idx := strings.LastIndex(before, ". "); idx >= 0 {
+		before = before[idx+2:]
+	}
+	// Walk forward from the end of the link text to the end of this sentence
+	if idx := strings.Index(after, ". "); idx >= 0 {
+		after = after[:idx+1]
+	}
+	return truncate(unmark(before + link + after))
+}
+
+// unmark drops the link markers and the extra spaces they leave
+func unmark(text string) string {
+	return strings.Join(strings.Fields(strings.NewReplacer("\x00", "", "\x01", "").Replace(text)), " ")
+}
+
+var (
+	commentPattern = regexp.MustCompile(`(?s)<!--.*?(-->|$)`) // A comment can go on past the end of the text
+	refPattern     = regexp.MustCompile(`(?s)<ref[^>/]*/>|<ref[^>]*>.*?</ref>`)
+	catPattern     = regexp.MustCompile(`(?i)\[\[\s*category:[^\]]*\]\]`)      // Shows up at the bottom of the page, not in the text
+	filePattern    = regexp.MustCompile(`(?i)\[\[(file|image):([^\[\]|]*\|)*`) // "[[File:x.jpg|thumb|" up to the caption
+	paramPattern   = regexp.MustCompile(`^\s*[\w ]+=`)
+	tagPattern     = regexp.MustCompile(`<[^>]+>`)
+	linkPattern    = regexp.MustCompile(`\[\[([^\]|]*\|)?([^\]]*)\]\]`)
+	extLinkPattern = regexp.MustCompile(`\[https?://[^\s\]]*\s?([^\]]*)\]`)
+)
+
+// plainText strips the wiki markup out of a chunk of wikitext, good enough for snippets not for rendering
+func plainText(text string) string {
+	text = commentPattern.ReplaceAllString(text, "")
+	text = refPattern.ReplaceAllString(text, "")
+	text = catPattern.ReplaceAllString(text, "")
+	text = filePattern.ReplaceAllString(text, "")
+	text = stripTemplates(text)
+	text = linkPattern.ReplaceAllString(text, "$2")
+	text = extLinkPattern.ReplaceAllString(text, "$1")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = strings.NewReplacer("'''", "", "''", "", "[[", "", "]]", "", "&nbsp;", " ").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// stripTemplates removes {{...}} blocks. A template holding the link we're after (marked by \x00), or left open,
+// keeps the argument with the link in it since that's the visible text, e.g. the spouse in {{marriage|...}}
+func stripTemplates(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "{{"):
+			inner, next := text[i+2:], len(text)
+			if end := templateEnd(text[i:]); end >= 0 {
+				inner, next = text[i+2:i+end], i+end+2
+			}
+			if strings.IndexByte(inner, 0) >= 0 {
+				b.WriteString(stripTemplates(linkArg(inner)))
+			}
+			i = next
+		case strings.HasPrefix(text[i:], "}}"):
+			i += 2 // Closes a template opened before the text starts
+		default:
+			b.WriteByte(text[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// templateEnd returns the index of the "}}" closing the template text starts with, -1 if it's never closed
+func templateEnd(text string) int {
+	depth := 0
+	for i := 0; i+1 < len(text); {
+		switch text[i : i+2] {
+		case "{{":
+			depth++
+			i += 2
+		case "}}":
+			if depth--; depth == 0 {
+				return i
+			}
+			i += 2
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// linkArg returns the argument of a template (the text between its braces) that holds the \x00 marker,
+// without its "name=", splitting only on the bars that aren't inside a link or a nested template
+func linkArg(inner string) string {
+	depth, start := 0, 0
+	for i := 0; i <= len(inner); i++ {
+		if i < len(inner) && inner[i] != '|' {
+			if rest := inner[i:]; strings.HasPrefix(rest, "[[") || strings.HasPrefix(rest, "{{") {
+				depth++
+				i++
+			} else if strings.HasPrefix(rest, "]]") || strings.HasPrefix(rest, "}}") {
+				depth = max(depth-1, 0)
+				i++
+			}
+			continue
+		}
+		if depth > 0 && i < len(inner) {
+			continue
+		}
+		arg := inner[start:i]
+		if start > 0 && strings.IndexByte(arg, 0) >= 0 {
+			return arg[len(paramPattern.FindString(arg)):]
+		}
+		start = i + 1
+	}
+	return ""
+}
+
+// truncate cuts a snippet down to maxSnippetRunes
+func truncate(s string) string {
+	if utf8.RuneCountInString(s) <= maxSnippetRunes {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:maxSnippetRunes])) + "…"
+}
@@ -0,0 +1,114 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+func TestExtractLinkContexts(t *testing.T) {
+	tests := []struct {
+		name     string
+		wikitext string
+		want     map[string]models.LinkContext
+	}{
+		{
+			name: "infobox rows",
+			wikitext: `{{Infobox scientist
+| name = Albert Einstein
+| doctoral_advisor = [[Alfred Kleiner]]
+| spouse = {{marriage|[[Mileva Marić]]|1903|1919|end=div}}<br />{{marriage|[[Elsa Löwenthal]]|1919|1936|end=died}}
+}}
+'''Albert Einstein''' was a German-born [[theoretical physicist]].`,
+			want: map[string]models.LinkContext{
+				"Alfred Kleiner":        {Location: models.LocationInfobox, Snippet: "doctoral_advisor: Alfred Kleiner"},
+				"Mileva Marić":          {Location: models.LocationInfobox, Snippet: "spouse: Mileva Marić"},
+				"Elsa Löwenthal":        {Location: models.LocationInfobox, Snippet: "spouse: Elsa Löwenthal"},
+				"Theoretical physicist": {Location: models.LocationBody, Snippet: "Albert Einstein was a German-born theoretical physicist."},
+			},
+		},
+		{
+			name: "links in a file caption",
+			wikitext: `== Career ==
+[[File:Einstein patentoffice.jpg|thumb|upright|Einstein at the [[Swiss Patent Office|patent office]] in [[Bern]], 1904]]
+He worked there until 1909.`,
+			want: map[string]models.LinkContext{
+				"Swiss Patent Office": {Section: "Career", Location: models.LocationBody, Snippet: "Einstein at the patent office in Bern, 1904"},
+				"Bern":                {Section: "Career", Location: models.LocationBody, Snippet: "Einstein at the patent office in Bern, 1904"},
+			},
+		},
+		{
+			// The template is never closed, so MediaWiki prints it and what follows is still prose
+			name: "unclosed template",
+			wikitext: `In 1905 he published {{lang|de|[[Annus mirabilis papers|four papers]]
+He moved to [[Berlin]] in 1914. He left in 1933.`,
+			want: map[string]models.LinkContext{
+				"Annus mirabilis papers": {Location: models.LocationBody, Snippet: "In 1905 he published four papers"},
+				"Berlin":                 {Location: models.LocationBody, Snippet: "He moved to Berlin in 1914."},
+			},
+		},
+		{
+			name: "comment over several lines",
+			wikitext: `He studied in [[Zurich]]. <!-- TODO: check
+the year --> He met [[Marcel Grossmann]] there. Later he taught.`,
+			want: map[string]models.LinkContext{
+				"Zurich":           {Location: models.LocationBody, Snippet: "He studied in Zurich."},
+				"Marcel Grossmann": {Location: models.LocationBody, Snippet: "He met Marcel Grossmann there."},
+			},
+		},
+		{
+			// The label is shorter than the link and has markup of its own, the snippet still ends at the sentence
+			name:     "piped labels",
+			wikitext: `He wrote the [[Einstein–Szilárd letter|letter]] with [[Leó Szilárd|Leó ''Szilárd'']] {{IPA|hu|ˈleːoː}} in 1939. It went to [[Franklin D. Roosevelt|Roosevelt]].`,
+			want: map[string]models.LinkContext{
+				"Einstein–Szilárd letter": {Location: models.LocationBody, Snippet: "He wrote the letter with Leó Szilárd in 1939."},
+				"Leó Szilárd":             {Location: models.LocationBody, Snippet: "He wrote the letter with Leó Szilárd in 1939."},
+				"Franklin D. Roosevelt":   {Location: models.LocationBody, Snippet: "It went to Roosevelt."},
+			},
+		},
+		{
+			// Language links go, short prefixes that aren't a language or namespace are part of the title
+			name: "interlanguage links",
+			wikitext: `See also [[:de:Albert Einstein|the German article]], [[abc: The Album]] and [[Category:Physicists]].
+[[de:Albert Einstein]]
+[[zh-yue:愛因斯坦]]`,
+			want: map[string]models.LinkContext{
+				"Abc: The Album": {Location: models.LocationBody, Snippet: "See also the German article, abc: The Album and ."},
+			},
+		},
+		{
+			name:     "navbox",
+			wikitext: "{{Nobel Prize in Physics Laureates|[[Max Planck]]}}",
+			want: map[string]models.LinkContext{
+				"Max Planck": {Location: models.LocationTemplate, Snippet: "Max Planck"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractLinkContexts(tt.wikitext); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkTarget(t *testing.T) {
+	tests := map[string]string{
+		"Albert Einstein":                "Albert Einstein",
+		"albert_Einstein#Early life|him": "Albert Einstein",
+		":Category:Physicists":           "",
+		"Image:Einstein 1921.jpg":        "",
+		"wikt:relativity":                "",
+		"fr:Albert Einstein":             "",
+		"be-tarask:Альберт Эйнштэйн":     "",
+		"ion: a novel":                   "Ion: a novel",
+		"M*A*S*H: The Movie":             "M*A*S*H: The Movie",
+	}
+	for inner, want := range tests {
+		if got := linkTarget(inner); got != want {
+			t.Errorf("linkTarget(%q) = %q, want %q", inner, got, want)
+		}
+	}
+}
@@ -1,137 +1,731 @@
 package fetcher
 
 import (
-	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"os"
-	"sync"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+	"github.com/Rani-Codes/sixth_degree/models"
 )
 
 type JobRequest struct {
-	Name string
+	Name  string // Seed name, this is what ends up as the key in the graph
+	Title string // Article title on the source wiki (same as Name for English)
 }
 
 type JobResult struct {
 	Name        string
+	Title       string
 	Connections []string
-	Error       error
+	Contexts    map[string]models.LinkContext // Connection -> where it sits in the article, only with LinkContext on
+	Others      []string                      // Links to pages that aren't seeds (yet), only kept in expansion mode
+	RevID       int64
+}
+
+// Failure is one entry in the failures file
+type Failure struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"` // Number of times we tried this page
+}
+
+// RunReport sums up a fetch so the caller can decide whether the build is good enough
+// It's also the final summary written to the report file
+type RunReport struct {
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Source     string           `json:"source"`
+	Total      int              `json:"total"`
+	Fetched    int              `json:"fetched"`
+	Reused     int              `json:"reused"`
+	Expanded   int              `json:"expanded"` // People added by expansion rounds, included in Total
+	Failed     int              `json:"failed"`
+	ByCategory map[string]int   `json:"byCategory"`
+	Progress   ProgressSnapshot `json:"progress"`
+	Failures   []Failure        `json:"-"` // Already in the failures file
+}
+
+// FailureRate is the fraction of pages that still failed after every retry pass
+func (r *RunReport) FailureRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Total)
 }
 
 type WorkerPool struct {
 	numWorkers int
 	validNames map[string]bool
-	jobs       chan JobRequest
-	results    chan JobResult
-	done       chan bool
-	wg         sync.WaitGroup
+	source     *LinkSource
+	titles     map[string]string // seed name -> title on source wiki, nil means titles match seed names
+	seeds      map[string]string // title on source wiki -> seed name
+	outFile    string
+
+	// A failed page gets up to Retries more attempts, each RetryDelay after the last one failed
+	Retries      int
+	RetryDelay   time.Duration
+	FailuresFile string // Where failures left after the last pass get written, empty skips it
+	ReportFile   string // Where the final summary goes, empty skips it
+	NodesFile    string // Where per person metadata (description, thumbnail, QID) goes, empty skips that fetch
+	ContextsFile string // When set, each article's wikitext is parsed for link context and written here
+	ProseFile    string // When set, a second graph with only links written in article prose (no infobox/navbox) goes here
+
+	// When RelationsFile is set, typed edges (spouse, employer, ...) between seeds are read from Wikidata and written there
+	RelationsFile string
+	Wikidata      *WikidataSource
+
+	// Sharded fetch: only the seeds with ShardOf(name, Shards) == Shard are fetched, links to every seed are still kept
+	// Shards of 0 or 1 fetches everything
+	Shard  int
+	Shards int
+
+	// Expansion mode: after the seeds are fetched, the ExpandTop most linked pages that turn out to be people become
+	// seeds too and get fetched, for up to ExpandRounds rounds or until there are MaxNodes seeds (0 means no cap)
+	ExpandRounds  int
+	ExpandTop     int
+	MaxNodes      int
+	ExpansionFile string // Who got added and why, empty skips it
+
+	// Previous build of the same graph as it is on disk (keyed by node ID), pages whose revision hasn't changed
+	// since are carried over instead of refetched
+	Previous         *models.GraphFile
+	PreviousContexts map[string]map[string]models.LinkContext // Previous contexts file, needed to reuse pages when classifying links
+
+	// Aggregated from every fetch
+	graph     map[string][]string
+	contexts  map[string]map[string]models.LinkContext
+	revisions map[string]int64
+	tags      models.Tags
+	failures  map[string]*Failure
+	reused    int
+	progress  *Progress
+	others    map[string][]string // Name -> links to non-seed pages, the expansion candidates
+	rejected  map[string]bool     // Candidates already found not to be people
+	redirects map[string]string   // Candidate link title -> the article it redirects to, for the ones checked so far
+	expansion ExpansionReport
 }
 
 // Constructor that initializes WorkerPool struct
-func NewWorkerPool(numWorkers int, validNames map[string]bool) *WorkerPool {
+func NewWorkerPool(numWorkers int, validNames map[string]bool, source *LinkSource, outFile string) *WorkerPool {
 	return &WorkerPool{
-		numWorkers: numWorkers,
-		validNames: validNames,
-		jobs:       make(chan JobRequest, 100),
-		results:    make(chan JobResult, 100),
-		done:       make(chan bool),
+		numWorkers:   numWorkers,
+		validNames:   validNames,
+		source:       source,
+		outFile:      outFile,
+		Retries:      1,
+		RetryDelay:   30 * time.Second,
+		FailuresFile: "failures.json",
+		ReportFile:   "fetch_report.json",
+		NodesFile:    "nodes.json",
+		graph:        make(map[string][]string),
+		contexts:     make(map[string]map[string]models.LinkContext),
+		revisions:    make(map[string]int64),
+		failures:     make(map[string]*Failure),
+		others:       make(map[string][]string),
+		rejected:     make(map[string]bool),
+		redirects:    make(map[string]string),
 	}
 }
 
-func (wp *WorkerPool) Producer(filename string) {
-	file, err := os.Open(filename)
+// SetTitles maps seed names to article titles on the source wiki (from TranslateTitles)
+// Seeds missing from the map have no article in that language and get skipped
+func (wp *WorkerPool) SetTitles(titles map[string]string) {
+	wp.titles = titles
+	wp.seeds = make(map[string]string, len(titles))
+	for name, title := range titles {
+		wp.seeds[title] = name
+	}
+}
+
+// titleFor returns the title to fetch for a seed name, ok is false if the seed has no article on the source wiki
+func (wp *WorkerPool) titleFor(name string) (string, bool) {
+	if wp.titles == nil {
+		return name, true
+	}
+	title, ok := wp.titles[name]
+	return title, ok
+}
+
+// seedFor maps a link title on the source wiki back to its seed name
+func (wp *WorkerPool) seedFor(title string) (string, bool) {
+	if wp.titles == nil {
+		return title, wp.validNames[title]
+	}
+	name, ok := wp.seeds[title]
+	return name, ok
+}
+
+// loadJobs reads the seed file into one job per seed that has an article on the source wiki
+func (wp *WorkerPool) loadJobs(filename string) ([]JobRequest, error) {
+	seeds, err := LoadSeeds(filename)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer file.Close()
+	wp.tags = SeedTags(seeds)
 
-	scanner := bufio.NewScanner(file)
+	var jobs []JobRequest
+	for _, seed := range seeds {
+		if wp.Shards > 1 && ShardOf(seed.Name, wp.Shards) != wp.Shard {
+			continue
+		}
+		title, ok := wp.titleFor(seed.Name)
+		if !ok {
+			log.Printf("Skipping %s: no %s article", seed.Name, wp.source.Host())
+			continue
+		}
+		jobs = append(jobs, JobRequest{Name: seed.Name, Title: title})
+	}
+	return jobs, nil
+}
 
-	// Reads file line by line, scanner. Scan returns true if there's a file left to read
-	for scanner.Scan() {
-		line := scanner.Text()
-		request := JobRequest{Name: line}
-		wp.jobs <- request
+// fetchJob is the pipeline's work: fetches one article and keeps the links that point at seeds
+func (wp *WorkerPool) fetchJob(ctx context.Context, job JobRequest) (JobResult, error) {
+	res := JobResult{Name: job.Name, Title: job.Title}
+	page, err := wp.source.FetchPage(ctx, job.Title)
+	if err != nil {
+		return res, err
 	}
+	res.RevID = page.RevID
 
-	close(wp.jobs)
+	var keptLinks []string
+	for _, link := range page.Links {
+		if name, ok := wp.seedFor(link); ok {
+			res.Connections = append(res.Connections, name)
+			keptLinks = append(keptLinks, link)
+		} else if wp.expanding() {
+			res.Others = append(res.Others, link)
+		}
+	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	// In expansion mode any other link may turn into an edge later, so its context is kept too
+	if wp.classifyLinks() && len(keptLinks)+len(res.Others) > 0 {
+		contexts, err := wp.linkContexts(ctx, job, append(keptLinks, res.Others...))
+		if err != nil && wp.ProseFile != "" {
+			// Without the wikitext we can't tell prose links apart, retry it like any other failed fetch
+			return res, err
+		} else if err != nil {
+			log.Printf("No link context for %s: %v", job.Name, err)
+		}
+		res.Contexts = contexts
 	}
+	return res, nil
+}
 
+// classifyLinks reports whether we need each article's wikitext to tell where its links sit
+func (wp *WorkerPool) classifyLinks() bool {
+	return wp.ContextsFile != "" || wp.ProseFile != ""
 }
 
-func (wp *WorkerPool) Worker() {
-	defer wp.wg.Done() //Signals this worker is done
+// linkContexts parses the article's wikitext to find where each kept link sits
+func (wp *WorkerPool) linkContexts(ctx context.Context, job JobRequest, links []string) (map[string]models.LinkContext, error) {
+	wikitext, err := wp.source.FetchWikitext(ctx, job.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	found := ExtractLinkContexts(wikitext)
+	contexts := make(map[string]models.LinkContext, len(links))
+	for _, link := range links {
+		name, _ := wp.seedFor(link)
+		contexts[name] = ContextFor(found, link)
+	}
+	return contexts, nil
+}
 
-	// Loop ends automatically when Producer closes job channel
-	for job := range wp.jobs {
-		links, err := FetchAllLinks(job.Name)
+// proseGraph keeps only the edges whose link is written in article prose
+// Navbox links ("List of presidents" and friends) make a lot of paths that are technically short but meaningless
+func (wp *WorkerPool) proseGraph() map[string][]string {
+	prose := make(map[string][]string, len(wp.graph))
+	for name, connections := range wp.graph {
+		kept := []string{}
+		for _, to := range connections {
+			if wp.contexts[name][to].Location == models.LocationBody {
+				kept = append(kept, to)
+			}
+		}
+		prose[name] = kept
+	}
+	return prose
+}
 
-		var validConnections []string
-		for _, link := range links {
-			if wp.validNames[link] {
-				validConnections = append(validConnections, link)
+// dropFailedLinks removes the links to seeds whose fetch failed, they'd be edges to nodes the graph doesn't have
+func (wp *WorkerPool) dropFailedLinks() {
+	for name, connections := range wp.graph {
+		kept := make([]string, 0, len(connections))
+		for _, to := range connections {
+			if _, fetched := wp.graph[to]; fetched || wp.failures[to] == nil {
+				kept = append(kept, to)
 			}
 		}
+		wp.graph[name] = kept
+	}
+}
 
-		res := JobResult{
-			Name:        job.Name,
-			Connections: validConnections,
-			Error:       err,
+// collect is the pipeline's sink, only this goroutine touches the aggregated maps
+func (wp *WorkerPool) collect(r Result[JobRequest, JobResult]) error {
+	// Failures are kept apart from the graph, a network error is not "links to nobody"
+	if r.Err != nil {
+		wp.progress.Failure()
+		log.Printf("Error on %s: %v", r.Job.Name, r.Err)
+		wp.failures[r.Job.Name] = &Failure{
+			Name:     r.Job.Name,
+			Title:    r.Job.Title,
+			Category: ErrorCategory(r.Err),
+			Error:    r.Err.Error(),
+			Attempts: r.Attempts,
 		}
+		return nil
+	}
 
-		wp.results <- res
+	res := r.Out
+	wp.progress.Success()
+	wp.graph[res.Name] = res.Connections
+	wp.revisions[res.Name] = res.RevID
+	if res.Others != nil {
+		wp.others[res.Name] = res.Others
+	}
+	if res.Contexts != nil {
+		wp.contexts[res.Name] = res.Contexts
 	}
+	return nil
 }
 
-func (wp *WorkerPool) Aggregator() {
+// pruneContexts drops the context of links that never became edges: the candidates expansion mode kept around and
+// the links to seeds whose fetch failed
+func (wp *WorkerPool) pruneContexts() {
+	for name, contexts := range wp.contexts {
+		kept := make(map[string]models.LinkContext, len(wp.graph[name]))
+		for _, to := range wp.graph[name] {
+			if ctx, ok := contexts[to]; ok {
+				kept[to] = ctx
+			}
+		}
+		wp.contexts[name] = kept
+	}
+}
 
-	graph := make(map[string][]string)
-	processed := 0
+// graphTags keeps the seed tags of people that made it into the graph
+func (wp *WorkerPool) graphTags() models.Tags {
+	tags := make(models.Tags)
+	for name := range wp.graph {
+		if t, ok := wp.tags[name]; ok {
+			tags[name] = t
+		}
+	}
+	return tags
+}
 
-	// Will wrap up when Run function closes results channel
-	for res := range wp.results {
-		graph[res.Name] = res.Connections
-		processed++
+// reuseUnchanged carries over pages that haven't changed since the previous build and returns the jobs left to fetch
+// Any doubt (different seed list, missing revision, missing link context) means the page gets refetched
+func (wp *WorkerPool) reuseUnchanged(ctx context.Context, jobs []JobRequest, seedHash string) []JobRequest {
+	prev := wp.Previous
+	if prev.Meta.SeedHash != seedHash {
+		// Unchanged pages could still link to newly added seeds, which their stored adjacency filtered out
+		log.Printf("Seed list changed since the previous build, doing a full refetch")
+		return jobs
+	}
+	if len(prev.Revisions) == 0 {
+		log.Printf("Previous build has no revision IDs, doing a full refetch")
+		return jobs
+	}
 
-		log.Printf("%d results processed", processed)
+	prevGraph, prevRevisions, unmatched := wp.previousBySeed(jobs)
+	if unmatched > 0 {
+		log.Printf("%d of %d seeds aren't in the previous build under their name or title, refetching them", unmatched, len(jobs))
+	}
 
-		if res.Error != nil {
-			log.Printf("Error on %s: %v", res.Name, res.Error)
+	titles := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		titles = append(titles, job.Title)
+	}
+	current, err := wp.source.FetchRevisions(ctx, titles)
+	if err != nil {
+		log.Printf("Couldn't check revision IDs (%v), doing a full refetch", err)
+		return jobs
+	}
+
+	var toFetch []JobRequest
+	for _, job := range jobs {
+		prevRev, cur := prevRevisions[job.Name], current[job.Title]
+		connections, inPrev := prevGraph[job.Name]
+		prevContexts, hasContexts := wp.PreviousContexts[job.Name]
+		if prevRev == 0 || prevRev != cur || !inPrev || (wp.classifyLinks() && !hasContexts && len(connections) > 0) {
+			toFetch = append(toFetch, job)
 			continue
 		}
+
+		wp.graph[job.Name] = connections
+		if hasContexts {
+			wp.contexts[job.Name] = prevContexts
+		}
+		wp.revisions[job.Name] = cur
+		wp.reused++
+	}
+
+	log.Printf("Reusing %d unchanged pages, refetching %d", wp.reused, len(toFetch))
+	return toFetch
+}
+
+// previousBySeed keys the previous build's adjacency and revisions by seed name again, the file on disk is keyed by
+// node ID. A node belongs to the seed its ID, label or one of its aliases names, either directly or as the seed's
+// title on the source wiki, so redirected seeds and other language editions still match. A page with a link that
+// maps back to no seed is left out and gets refetched. unmatched counts the jobs with no node in the previous build.
+func (wp *WorkerPool) previousBySeed(jobs []JobRequest) (adjacency map[string][]string, revisions map[string]int64, unmatched int) {
+	prev := wp.Previous
+	seedOf := func(name string) (string, bool) {
+		if seed, ok := wp.seedFor(name); ok {
+			return seed, true
+		}
+		return name, wp.validNames[name]
+	}
+
+	// Every name a node went by, sorted so a node that two seeds map to always answers to the same one
+	names := make(map[string][]string, len(prev.Graph))
+	for id := range prev.Graph {
+		names[id] = append(names[id], id)
+		if label := prev.Labels[id]; label != "" {
+			names[id] = append(names[id], label)
+		}
+	}
+	for alias, id := range prev.Aliases {
+		if _, ok := prev.Graph[id]; ok {
+			names[id] = append(names[id], alias)
+		}
+	}
+	idOf := make(map[string]string)
+	seedOfID := make(map[string]string, len(prev.Graph))
+	for id, all := range names {
+		sort.Strings(all)
+		for _, name := range all {
+			seed, ok := seedOf(name)
+			if !ok {
+				continue
+			}
+			if _, taken := idOf[seed]; !taken {
+				idOf[seed] = id
+			}
+			if _, ok := seedOfID[id]; !ok {
+				seedOfID[id] = seed
+			}
+		}
 	}
 
-	data, err := json.MarshalIndent(graph, "", "  ")
+	adjacency = make(map[string][]string, len(jobs))
+	revisions = make(map[string]int64, len(jobs))
+jobs:
+	for _, job := range jobs {
+		id, ok := idOf[job.Name]
+		if !ok {
+			unmatched++
+			continue
+		}
+		neighbors := make([]string, 0, len(prev.Graph[id]))
+		for _, to := range prev.Graph[id] {
+			seed, ok := seedOfID[to]
+			if !ok {
+				continue jobs
+			}
+			neighbors = append(neighbors, seed)
+		}
+		adjacency[job.Name] = neighbors
+		revisions[job.Name] = prev.Revisions[id]
+	}
+	return adjacency, revisions, unmatched
+}
+
+// fetch runs jobs through the pipeline, a page that fails gets up to Retries more attempts while the rest carry on
+func (wp *WorkerPool) fetch(ctx context.Context, jobs []JobRequest) error {
+	wp.progress.AddTotal(len(jobs))
+	p := NewPipeline(wp.numWorkers, wp.fetchJob)
+	p.Retries = wp.Retries
+	p.RetryDelay = wp.RetryDelay
+	p.OnRetry = func(job JobRequest, err error, attempt int) {
+		wp.progress.Retry()
+		log.Printf("Error on %s (attempt %d, retrying in %s): %v", job.Name, attempt, wp.RetryDelay, err)
+	}
+	return p.Run(ctx, slices.Values(jobs), wp.collect)
+}
+
+// fetchAll fetches the seeds and then the people each expansion round adds, returning how many were added
+func (wp *WorkerPool) fetchAll(ctx context.Context, jobs []JobRequest) (int, error) {
+	if err := wp.fetch(ctx, jobs); err != nil {
+		return 0, err
+	}
+
+	// Expansion rounds add the most linked people to the seeds and fetch them too
+	expanded := 0
+	for round := 1; round <= wp.ExpandRounds; round++ {
+		added := wp.expand(ctx, round)
+		if len(added) == 0 {
+			break
+		}
+		expanded += len(added)
+		if err := wp.fetch(ctx, added); err != nil {
+			return expanded, err
+		}
+	}
+	return expanded, ctx.Err() // A cancel during an expansion lookup just ends the rounds early
+}
+
+// Orchestration function, runs the seeds through the fetch pipeline (the metaphorical assembly line) and writes the outputs
+// Pages that still fail after their retries go to the failures file. When ctx is cancelled nothing gets written
+// and the context's error comes back, a half fetched graph would look like a lot of people with no links.
+func (wp *WorkerPool) Run(ctx context.Context, filename string) (*RunReport, error) {
+	startedAt := time.Now().UTC()
+	jobs, err := wp.loadJobs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	seedHash, err := hashFile(filename)
 	if err != nil {
-		log.Fatalf("failed to marshal graph: %v", err)
+		return nil, fmt.Errorf("failed to hash seed file: %w", err)
 	}
 
-	err = os.WriteFile("graph.json", data, 0644)
+	toFetch := jobs
+	if wp.Previous != nil {
+		toFetch = wp.reuseUnchanged(ctx, jobs, seedHash)
+	}
+	// Live status line on a terminal, periodic log lines otherwise
+	wp.progress = NewProgress(&wp.source.Stats)
+	wp.progress.Start()
+	expanded, err := wp.fetchAll(ctx, toFetch)
+	wp.progress.Stop()
 	if err != nil {
-		log.Fatalf("failed to write graph.json: %v", err)
+		return nil, err
 	}
+	wp.dropFailedLinks()
 
-	wp.done <- true
+	// Metadata first, its QIDs become the graph's node IDs
+	var nodes map[string]models.Person
+	if wp.NodesFile != "" || wp.RelationsFile != "" {
+		nodes = wp.fetchNodes(ctx)
+		// Without the metadata the graph would be keyed by title, don't write that because of a Ctrl-C
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if wp.NodesFile != "" && nodes != nil {
+			if err := graph.WriteNodes(wp.NodesFile, nodes); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", wp.NodesFile, err)
+			}
+			log.Printf("Wrote metadata for %d people to %s", len(nodes), wp.NodesFile)
+		}
+		// Relations need the QIDs from the metadata
+		if wp.RelationsFile != "" && nodes != nil {
+			if err := wp.writeRelations(ctx, nodes); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	meta := models.GraphMeta{
+		FetchedAt: time.Now().UTC(),
+		SeedFile:  filepath.Base(filename),
+		SeedHash:  seedHash,
+		Source:    wp.source.Host(),
+		Failed:    len(wp.failures),
+		Reused:    wp.reused,
+	}
+	if wp.Shards > 1 {
+		meta.Shard = &models.Shard{Index: wp.Shard, Count: wp.Shards, Assigned: make([]string, 0, len(jobs))}
+		for _, job := range jobs {
+			meta.Shard.Assigned = append(meta.Shard.Assigned, job.Name)
+		}
+		sort.Strings(meta.Shard.Assigned)
+	}
+	// Written to a temp file then renamed, a crash never leaves a truncated graph behind
+	file := wp.graphFile(wp.graph, meta, nodes)
+	if err := graph.WriteGraphFile(wp.outFile, file); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", wp.outFile, err)
+	}
+
+	if wp.ProseFile != "" {
+		prose := wp.graphFile(wp.proseGraph(), meta, nodes)
+		if err := graph.WriteGraphFile(wp.ProseFile, prose); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", wp.ProseFile, err)
+		}
+		log.Printf("Wrote prose-only graph (%d of %d edges) to %s", prose.Meta.Edges, file.Meta.Edges, wp.ProseFile)
+	}
+	if wp.ContextsFile != "" {
+		wp.pruneContexts()
+		if err := graph.WriteContexts(wp.ContextsFile, wp.contexts); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", wp.ContextsFile, err)
+		}
+		log.Printf("Wrote link context for %d pages to %s", len(wp.contexts), wp.ContextsFile)
+	}
+
+	report := &RunReport{
+		StartedAt:  startedAt,
+		Source:     wp.source.Host(),
+		Total:      len(jobs) + expanded,
+		Fetched:    len(wp.graph),
+		Reused:     wp.reused,
+		Expanded:   expanded,
+		Failed:     len(wp.failures),
+		ByCategory: make(map[string]int),
+		Progress:   wp.progress.Snapshot(),
+	}
+	report.Failures = make([]Failure, 0, len(wp.failures)) // [] instead of null in the JSON
+	for _, f := range wp.failures {
+		report.Failures = append(report.Failures, *f)
+		report.ByCategory[f.Category]++
+	}
+	// Group by category then name so the file diffs nicely between runs
+	sort.Slice(report.Failures, func(i, j int) bool {
+		a, b := report.Failures[i], report.Failures[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Name < b.Name
+	})
+
+	if wp.FailuresFile != "" {
+		if err := wp.writeFailures(report); err != nil {
+			return nil, err
+		}
+	}
+	if wp.expanding() && wp.ExpansionFile != "" {
+		if err := wp.writeExpansion(); err != nil {
+			return nil, err
+		}
+	}
+	report.FinishedAt = time.Now().UTC()
+	if wp.ReportFile != "" {
+		if err := wp.writeReport(report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// graphFile wraps a seed name keyed graph in the envelope and rekeys it by QID
+// Names the previous build knew its nodes by stay around as aliases, so a renamed article is still found by its old title
+func (wp *WorkerPool) graphFile(g map[string][]string, meta models.GraphMeta, nodes map[string]models.Person) *models.GraphFile {
+	file := &models.GraphFile{
+		Meta:      meta,
+		Graph:     g,
+		Revisions: wp.revisions,
+		Tags:      wp.graphTags(),
+	}
+	graph.AssignIDs(file, nodes)
+	graph.KeepAliases(file, wp.Previous)
+	return file
+}
+
+// fetchNodes fetches metadata (description, thumbnail, QID) for every page that made it into the graph
+// Metadata is nice to have, so a failure here gets logged instead of failing the whole fetch
+func (wp *WorkerPool) fetchNodes(ctx context.Context) map[string]models.Person {
+	titles := make([]string, 0, len(wp.graph))
+	for name := range wp.graph {
+		title, _ := wp.titleFor(name)
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	log.Printf("Fetching metadata for %d pages", len(titles))
+	byTitle, err := wp.source.FetchMetadata(ctx, titles)
+	if err != nil {
+		log.Printf("Metadata fetch failed: %v", err)
+		return nil
+	}
+
+	// Key by seed name like the graph, the fetched title only differs for other language editions
+	nodes := make(map[string]models.Person, len(byTitle))
+	for title, person := range byTitle {
+		name, ok := wp.seedFor(title)
+		if !ok {
+			continue
+		}
+		person.Name = name
+		nodes[name] = person
+	}
+	return nodes
+}
+
+// writeRelations builds typed Wikidata edges between seeds using the QIDs from the metadata fetch
+// Like metadata they're nice to have, only a failed write or a cancel is an error
+func (wp *WorkerPool) writeRelations(ctx context.Context, nodes map[string]models.Person) error {
+	qids := make(map[string]string, len(nodes))
+	for name, person := range nodes {
+		qids[name] = person.QID
+	}
+
+	log.Printf("Fetching Wikidata relations for %d people", len(qids))
+	typed, err := wp.Wikidata.FetchRelations(ctx, qids)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		log.Printf("Skipping %s, Wikidata fetch failed: %v", wp.RelationsFile, err)
+		return nil
+	}
+
+	if err := graph.WriteRelations(wp.RelationsFile, typed); err != nil {
+		return fmt.Errorf("failed to write %s: %w", wp.RelationsFile, err)
+	}
+	log.Printf("Wrote typed relations for %d people to %s", len(typed), wp.RelationsFile)
+	return nil
 }
 
-// Orchestration function, sets up goroutines (starts the metaphorical assembly line)
-func (wp *WorkerPool) Run(filename string) {
-	go wp.Producer(filename)
-	go wp.Aggregator()
+// writeFailures writes the failures manifest, grouped by error category
+func (wp *WorkerPool) writeFailures(report *RunReport) error {
+	manifest := struct {
+		Total      int            `json:"total"`
+		Failed     int            `json:"failed"`
+		ByCategory map[string]int `json:"byCategory"`
+		Failures   []Failure      `json:"failures"`
+	}{report.Total, report.Failed, report.ByCategory, report.Failures}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal failures: %w", err)
+	}
+	if err := graph.WriteFileAtomic(wp.FailuresFile, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", wp.FailuresFile, err)
+	}
+	log.Printf("Wrote %d failures to %s %v", report.Failed, wp.FailuresFile, report.ByCategory)
+	return nil
+}
 
-	for i := 0; i < wp.numWorkers; i++ {
-		wp.wg.Add(1) // Add 1 more to the WaitGroup
-		go wp.Worker()
+// writeReport writes the final summary: counts, throughput, retries and 429s for the whole run
+func (wp *WorkerPool) writeReport(report *RunReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fetch report: %w", err)
 	}
+	if err := graph.WriteFileAtomic(wp.ReportFile, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", wp.ReportFile, err)
+	}
+	p := report.Progress
+	log.Printf("Done in %s: %d fetched, %d reused, %d failed, %d requests (%.1f req/s), %d retries, %d rate limited. Report in %s",
+		(time.Duration(p.ElapsedSeconds) * time.Second).String(), report.Fetched-report.Reused, report.Reused, report.Failed,
+		p.Requests, p.RequestsPerSec, p.Retries, p.RateLimited, wp.ReportFile)
+	return nil
+}
 
-	// Separate goroutine waits for all workers to finish then closes results channel
-	go func() {
-		wp.wg.Wait()      // Wait until all workers signal done
-		close(wp.results) // Once wait done now it's safe to close results channel
-	}()
+// hashFile returns the hex sha256 of a file, used to record which seed list a graph was built from
+func hashFile(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
 
-	<-wp.done
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
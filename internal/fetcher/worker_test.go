@@ -0,0 +1,235 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// revisionsFixture serves prop=info with the latest revision of each title, unknown titles come back missing
+func revisionsFixture(t *testing.T, revisions map[string]int64) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("prop") != "info" {
+			http.Error(w, "unexpected prop "+q.Get("prop"), http.StatusBadRequest)
+			return
+		}
+		pages := make(map[string]any)
+		for i, title := range strings.Split(q.Get("titles"), "|") {
+			if rev, ok := revisions[title]; ok {
+				pages[fmt.Sprint(i+1)] = map[string]any{"title": title, "lastrevid": rev}
+			} else {
+				pages[fmt.Sprint(-i-1)] = map[string]any{"title": title, "missing": ""}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]any{"query": map[string]any{"pages": pages}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// curiePrevious is the previous build as it's on disk, keyed by QID: Irene's seed name now redirects to her
+// current title, which is her label, and the seed name stayed behind as an alias
+func curiePrevious() *models.GraphFile {
+	return &models.GraphFile{
+		Version: models.GraphFileVersion,
+		Meta:    models.GraphMeta{SeedHash: "seeds-v1"},
+		Graph: models.Graph{
+			"Q1": {"Q2", "Q3"},
+			"Q2": {"Q1"},
+			"Q3": {"Q1", "Q2"},
+		},
+		Revisions: map[string]int64{"Q1": 10, "Q2": 20, "Q3": 30},
+		Labels:    map[string]string{"Q1": "Marie Curie", "Q2": "Pierre Curie", "Q3": "Irène Joliot-Curie"},
+		Aliases:   map[string]string{"Irene Curie": "Q3"},
+	}
+}
+
+func TestReuseUnchanged(t *testing.T) {
+	seeds := []string{"Marie Curie", "Pierre Curie", "Irene Curie"}
+
+	tests := []struct {
+		name     string
+		titles   map[string]string // Seed name -> title on the source wiki, nil for English
+		previous func(prev *models.GraphFile)
+		seedHash string
+		current  map[string]int64 // Latest revision by title
+		reused   map[string][]string
+		fetch    []string
+	}{
+		{
+			name:     "unchanged pages are carried over under their seed names",
+			seedHash: "seeds-v1",
+			current:  map[string]int64{"Marie Curie": 10, "Pierre Curie": 21, "Irene Curie": 30},
+			reused: map[string][]string{
+				"Marie Curie": {"Pierre Curie", "Irene Curie"},
+				"Irene Curie": {"Marie Curie", "Pierre Curie"},
+			},
+			fetch: []string{"Pierre Curie"},
+		},
+		{
+			name:   "another language edition matches by title",
+			titles: map[string]string{"Marie Curie": "Marie Curie", "Pierre Curie": "Pierre Curie", "Irene Curie": "Irène Joliot-Curie"},
+			previous: func(prev *models.GraphFile) {
+				prev.Aliases = nil
+			},
+			seedHash: "seeds-v1",
+			current:  map[string]int64{"Marie Curie": 10, "Pierre Curie": 20, "Irène Joliot-Curie": 30},
+			reused: map[string][]string{
+				"Marie Curie":  {"Pierre Curie", "Irene Curie"},
+				"Pierre Curie": {"Marie Curie"},
+				"Irene Curie":  {"Marie Curie", "Pierre Curie"},
+			},
+		},
+		{
+			name:     "a changed seed list refetches everything",
+			seedHash: "seeds-v2",
+			current:  map[string]int64{"Marie Curie": 10, "Pierre Curie": 20, "Irene Curie": 30},
+			fetch:    seeds,
+		},
+		{
+			name: "a seed the previous build doesn't know is refetched",
+			previous: func(prev *models.GraphFile) {
+				prev.Aliases = nil
+			},
+			seedHash: "seeds-v1",
+			current:  map[string]int64{"Marie Curie": 10, "Pierre Curie": 20, "Irene Curie": 30},
+			reused:   map[string][]string{"Pierre Curie": {"Marie Curie"}},
+			// Both Marie and Irene link to Irene, whose node no seed maps to anymore
+			fetch: []string{"Marie Curie", "Irene Curie"},
+		},
+		{
+			name: "a previous build without revisions refetches everything",
+			previous: func(prev *models.GraphFile) {
+				prev.Revisions = nil
+			},
+			seedHash: "seeds-v1",
+			current:  map[string]int64{"Marie Curie": 10, "Pierre Curie": 20, "Irene Curie": 30},
+			fetch:    seeds,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validNames := make(map[string]bool)
+			for _, name := range seeds {
+				validNames[name] = true
+			}
+			source := NewLinkSource("en")
+			source.endpoint = revisionsFixture(t, tt.current).URL
+			wp := NewWorkerPool(1, validNames, source, "")
+			if tt.titles != nil {
+				wp.SetTitles(tt.titles)
+			}
+			wp.Previous = curiePrevious()
+			if tt.previous != nil {
+				tt.previous(wp.Previous)
+			}
+
+			var jobs []JobRequest
+			for _, name := range seeds {
+				title, _ := wp.titleFor(name)
+				jobs = append(jobs, JobRequest{Name: name, Title: title})
+			}
+			toFetch := wp.reuseUnchanged(context.Background(), jobs, tt.seedHash)
+
+			var fetch []string
+			for _, job := range toFetch {
+				fetch = append(fetch, job.Name)
+			}
+			sort.Strings(fetch)
+			want := append([]string(nil), tt.fetch...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(fetch, want) {
+				t.Errorf("refetching %v, want %v", fetch, want)
+			}
+
+			reused := tt.reused
+			if reused == nil {
+				reused = map[string][]string{}
+			}
+			if !reflect.DeepEqual(wp.graph, reused) {
+				t.Errorf("reused %v, want %v", wp.graph, reused)
+			}
+			if wp.reused != len(reused) {
+				t.Errorf("counted %d reused pages, want %d", wp.reused, len(reused))
+			}
+			for name := range reused {
+				if wp.revisions[name] == 0 {
+					t.Errorf("no revision kept for %s", name)
+				}
+			}
+		})
+	}
+}
+
+// linksFixture serves prop=links|info for the given pages, any other title comes back missing
+func linksFixture(t *testing.T, links map[string][]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("prop") != "links|info" {
+			http.Error(w, "unexpected prop "+q.Get("prop"), http.StatusBadRequest)
+			return
+		}
+		title := q.Get("titles")
+		page := map[string]any{"title": title, "missing": ""}
+		if targets, ok := links[title]; ok {
+			page = map[string]any{"title": title, "lastrevid": 1, "links": []map[string]any{}}
+			for _, to := range targets {
+				page["links"] = append(page["links"].([]map[string]any), map[string]any{"ns": 0, "title": to})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]any{"query": map[string]any{"pages": map[string]any{"1": page}}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// A seed whose page can't be fetched is a failure, not a node the others still link to
+func TestRunFailedSeed(t *testing.T) {
+	dir := t.TempDir()
+	seedFile := filepath.Join(dir, "seeds.txt")
+	if err := os.WriteFile(seedFile, []byte("Marie Curie\nPierre Curie\nIrene Curie\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Irene's article is gone, both her parents still link to it
+	source := NewLinkSource("en")
+	source.endpoint = linksFixture(t, map[string][]string{
+		"Marie Curie":  {"Pierre Curie", "Irene Curie", "Paris"},
+		"Pierre Curie": {"Marie Curie", "Irene Curie"},
+	}).URL
+	validNames := map[string]bool{"Marie Curie": true, "Pierre Curie": true, "Irene Curie": true}
+	out := filepath.Join(dir, "graph.json")
+	wp := NewWorkerPool(2, validNames, source, out)
+	wp.FailuresFile, wp.ReportFile, wp.NodesFile = "", "", ""
+
+	report, err := wp.Run(context.Background(), seedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 1 || report.Failures[0].Name != "Irene Curie" {
+		t.Errorf("failures = %+v", report.Failures)
+	}
+	ds, err := graph.LoadDataset(out, graph.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Graph{"Marie Curie": {"Pierre Curie"}, "Pierre Curie": {"Marie Curie"}}
+	if got := ds.LabelGraph(); !reflect.DeepEqual(got, want) {
+		t.Errorf("graph = %v, want %v", got, want)
+	}
+}
@@ -2,12 +2,10 @@ package graph
 
 import (
 	"fmt"
-
-	"github.com/Rani-Codes/sixth_degree/models"
 )
 
-// BFS algorithm
-func FindShortestPath(graph models.Graph, startNode, endNode string, updateCallback func(level int, node string)) ([]string, error) {
+// BFS algorithm, counts hops so edge weights don't change the path
+func FindShortestPath(graph *NodeGraph, startNode, endNode string, updateCallback func(level int, node string)) ([]string, error) {
 	queue := []string{startNode}
 	parent := make(map[string]string)           // using make to get an empty map (to be filled later)
 	visited := map[string]bool{startNode: true} // literal definition since we have default content
@@ -15,11 +13,11 @@ func FindShortestPath(graph models.Graph, startNode, endNode string, updateCallb
 
 	// New concept learned, Go’s comma-ok idiom (useful for safe lookup on maps)
 	//	ok returns true if the key exists in the map otherwise exits with error of what went wrong
-	if _, ok := graph[startNode]; !ok {
+	if !graph.Has(startNode) {
 		return nil, fmt.Errorf("start node %q not found in graph", startNode)
 	}
 
-	if _, ok := graph[endNode]; !ok {
+	if !graph.Has(endNode) {
 		return nil, fmt.Errorf("end node %q not found in graph", endNode)
 	}
 
@@ -48,11 +46,12 @@ func FindShortestPath(graph models.Graph, startNode, endNode string, updateCallb
 				}
 				return path, nil
 			}
-			for _, neighbor := range graph[current] {
-				if !visited[neighbor] {
-					visited[neighbor] = true
-					parent[neighbor] = current
-					queue = append(queue, neighbor)
+			// A dangling edge has no neighbors, nothing to follow from there
+			for _, to := range graph.Neighbors(current) {
+				if !visited[to] {
+					visited[to] = true
+					parent[to] = current
+					queue = append(queue, to)
 				}
 			}
 
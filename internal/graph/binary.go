@@ -0,0 +1,547 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// The binary format is what the server loads at startup: no JSON to decode, the file gets mapped into memory
+// and searched where it is. cmd/convert makes one from any other graph file.
+//
+// Everything is little endian. A 64 byte header, then the sections in this order, each padded to 4 bytes:
+//
+//	string offsets  uint32 × (2 × nodes + 1)  node i's ID is strings[off[i]:off[i+1]], its label at off[nodes+i]
+//	link offsets    uint32 × (nodes + 1)      CSR: node i links to edges[link[i]:link[i+1]]
+//	edges           uint32 × edges            node indexes
+//	node flags      uint8 × nodes             nodePerson, nodeTargetOnly
+//	strings         the bytes of every ID then every label (empty when the label is the ID)
+//	extra           JSON with the rest of the envelope: meta, revisions, tags, aliases and attributes
+//
+// Nodes are sorted by ID, so finding one is a binary search over the string table. Target only nodes come
+// after the others, sorted among themselves.
+//
+// The header's checksum is a CRC-32C of the whole file, header included with the checksum itself zeroed, so a
+// truncated or corrupted file is refused instead of loading as a graph with holes in it. A checksum only catches
+// accidents though, so the offsets and edge targets get range checked on load as well: a file that's wrong but
+// consistent fails to load instead of panicking in the middle of a search.
+
+// binaryMagic starts every binary graph file
+var binaryMagic = [8]byte{'S', 'X', 'D', 'G', 'R', 'A', 'P', 'H'}
+
+// BinaryVersion is the current layout of the binary format, bumped whenever the sections change
+// Version 2 extended the checksum to the header.
+const BinaryVersion = 2
+
+const binaryHeaderSize = 64
+
+// binaryChecksumAt is where the checksum sits in the header
+const binaryChecksumAt = 36
+
+// Header flags
+const flagPeople = 1 // nodePerson is set, the graph is a full article graph
+
+// Node flags
+const (
+	nodePerson     = 1 << 0 // A person in a full article graph
+	nodeTargetOnly = 1 << 1 // Only a link target, the graph has no entry for it (files from before the writer validated)
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// binaryHeader is the fixed size start of the file, in on-disk order
+type binaryHeader struct {
+	Magic        [8]byte
+	Version      uint32 // Layout version, BinaryVersion
+	GraphVersion uint32 // Envelope version of the graph it holds (models.GraphFileVersion)
+	Nodes        uint32
+	Edges        uint32
+	Flags        uint32
+	StringBytes  uint32
+	ExtraBytes   uint32
+	Checksum     uint32
+	_            [24]byte
+}
+
+// binaryExtra is the part of the envelope that isn't adjacency or labels
+type binaryExtra struct {
+	Meta      models.GraphMeta                       `json:"meta"`
+	Revisions map[string]int64                       `json:"revisions,omitempty"`
+	Tags      models.Tags                            `json:"tags,omitempty"`
+	Aliases   map[string]string                      `json:"aliases,omitempty"`
+	Attrs     map[string]map[string]string           `json:"attrs,omitempty"`
+	EdgeAttrs map[string]map[string]models.EdgeAttrs `json:"edgeAttrs,omitempty"`
+}
+
+// binaryChecksum is the CRC-32C of a file made of header and body, with the header's checksum counted as zero
+func binaryChecksum(header, body []byte) uint32 {
+	var h [binaryHeaderSize]byte
+	copy(h[:], header)
+	clear(h[binaryChecksumAt : binaryChecksumAt+4])
+	return crc32.Update(crc32.Checksum(h[:], castagnoli), castagnoli, body)
+}
+
+// pad4 rounds a section length up to the next multiple of 4
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// WriteBinaryGraph writes file in the binary format, people marks the people of a full article graph (nil for others)
+// Nodes are sorted by ID and neighbor lists normalized, so the same graph always gives the same bytes. The graph
+// is repaired like a lenient load would (see Validate) on the way, the server searches the file as it is.
+// file itself isn't changed.
+func WriteBinaryGraph(path string, file *models.GraphFile, people map[string]bool) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		return encodeBinaryGraph(w, file, people)
+	})
+}
+
+// encodeBinaryGraph is WriteBinaryGraph to any writer, e.g. a gzip one for the graphs built into the server
+func encodeBinaryGraph(w io.Writer, file *models.GraphFile, people map[string]bool) error {
+	// A copy of the map is enough, AssignIDs, Normalize and Validate replace neighbor lists rather than edit them
+	c := *file
+	c.Graph = maps.Clone(file.Graph)
+	file = &c
+	AssignIDs(file, nil)
+	Normalize(file.Graph)
+	// Dangling edges and the rest get dropped here, so every node has a neighbor list
+	report, err := Validate(file.Graph, Lenient)
+	if err != nil {
+		return err
+	}
+	if len(report.Issues) > 0 {
+		log.Printf("Repaired before writing the binary graph: %s", report.Summary())
+	}
+
+	ids := sortedKeys(file.Graph)
+	index := make(map[string]uint32, len(ids))
+	for i, id := range ids {
+		index[id] = uint32(i)
+	}
+
+	edges := CountEdges(file.Graph)
+	if len(ids) > math.MaxInt32 || uint64(edges) > math.MaxUint32 {
+		return fmt.Errorf("graph too big for the binary format: %d nodes, %d edges", len(ids), edges)
+	}
+
+	var body bytes.Buffer
+	put := func(v uint32) {
+		body.Write(binary.LittleEndian.AppendUint32(nil, v))
+	}
+	pad := func() {
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	// String offsets, IDs then labels
+	var strs bytes.Buffer
+	put(0)
+	for _, id := range ids {
+		strs.WriteString(id)
+		put(uint32(strs.Len()))
+	}
+	for _, id := range ids {
+		strs.WriteString(file.Labels[id])
+		put(uint32(strs.Len()))
+	}
+	if uint64(strs.Len()) > math.MaxUint32 {
+		return fmt.Errorf("graph too big for the binary format: %d bytes of strings", strs.Len())
+	}
+
+	// CSR offsets and edges
+	offset := uint32(0)
+	put(offset)
+	for _, id := range ids {
+		offset += uint32(len(file.Graph[id]))
+		put(offset)
+	}
+	for _, id := range ids {
+		for _, to := range file.Graph[id] {
+			put(index[to])
+		}
+	}
+
+	header := binaryHeader{
+		Magic:        binaryMagic,
+		Version:      BinaryVersion,
+		GraphVersion: models.GraphFileVersion,
+		Nodes:        uint32(len(ids)),
+		Edges:        uint32(edges),
+		StringBytes:  uint32(strs.Len()),
+	}
+	if people != nil {
+		header.Flags |= flagPeople
+	}
+	for _, id := range ids {
+		var f byte
+		if people[id] {
+			f |= nodePerson
+		}
+		body.WriteByte(f)
+	}
+	pad()
+	body.Write(strs.Bytes())
+	pad()
+
+	file.Meta.Nodes = len(file.Graph)
+	file.Meta.Edges = edges
+	extra, err := json.Marshal(binaryExtra{Meta: file.Meta, Revisions: file.Revisions, Tags: file.Tags, Aliases: file.Aliases, Attrs: file.Attrs, EdgeAttrs: file.EdgeAttrs})
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph metadata: %w", err)
+	}
+	body.Write(extra)
+	header.ExtraBytes = uint32(len(extra))
+
+	var head bytes.Buffer
+	if err := binary.Write(&head, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(head.Bytes()[binaryChecksumAt:], binaryChecksum(head.Bytes(), body.Bytes()))
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Write(body.Bytes())
+	return err
+}
+
+// IsBinaryGraph reports whether a file starts with the binary format's magic bytes
+func IsBinaryGraph(filename string) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, fmt.Errorf("failed to open graph file: %w", err)
+	}
+	defer f.Close()
+
+	var magic [8]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return false, nil // Shorter than the magic, can't be binary
+	}
+	return magic == binaryMagic, nil
+}
+
+// binaryGraph reads a binary graph in place: nothing is decoded up front, IDs, labels and neighbor lists are
+// read out of data as they're asked for. It's the adjacency of a NodeGraph loaded from a binary file.
+type binaryGraph struct {
+	data    []byte
+	header  binaryHeader
+	nodes   int // Every node, target only ones included
+	entries int // Nodes with a neighbor list, they come first
+
+	strOffsAt, linkOffsAt, edgesAt, flagsAt, stringsAt, extraAt int
+}
+
+// parseBinaryGraph checks a binary graph's header, size, checksum and offsets and finds its sections
+func parseBinaryGraph(data []byte) (*binaryGraph, error) {
+	var h binaryHeader
+	if len(data) < binaryHeaderSize {
+		return nil, fmt.Errorf("too short for a binary graph")
+	}
+	if err := binary.Read(bytes.NewReader(data[:binaryHeaderSize]), binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if h.Magic != binaryMagic {
+		return nil, fmt.Errorf("not a binary graph")
+	}
+	if h.Version != BinaryVersion {
+		return nil, fmt.Errorf("binary layout version %d, this build reads %d (rerun cmd/convert)", h.Version, BinaryVersion)
+	}
+	if h.GraphVersion > models.GraphFileVersion {
+		return nil, fmt.Errorf("version %d, this build reads up to %d", h.GraphVersion, models.GraphFileVersion)
+	}
+
+	n, e := int(h.Nodes), int(h.Edges)
+	g := &binaryGraph{data: data, header: h, nodes: n}
+	g.strOffsAt = binaryHeaderSize
+	g.linkOffsAt = g.strOffsAt + 4*(2*n+1)
+	g.edgesAt = g.linkOffsAt + 4*(n+1)
+	g.flagsAt = g.edgesAt + 4*e
+	g.stringsAt = g.flagsAt + pad4(n)
+	g.extraAt = g.stringsAt + pad4(int(h.StringBytes))
+	if end := g.extraAt + int(h.ExtraBytes); len(data) != end {
+		return nil, fmt.Errorf("is %d bytes, the header says %d (truncated?)", len(data), end)
+	}
+	if sum := binaryChecksum(data, data[binaryHeaderSize:]); sum != h.Checksum {
+		return nil, fmt.Errorf("checksum mismatch (%08x, header says %08x), the file is corrupted", sum, h.Checksum)
+	}
+	if err := g.checkOffsets(); err != nil {
+		return nil, err
+	}
+	g.entries = sort.Search(n, func(i int) bool { return data[g.flagsAt+i]&nodeTargetOnly != 0 })
+	return g, nil
+}
+
+// checkOffsets makes sure every string offset, link offset and edge target points inside its section, so
+// reading the graph can't go out of bounds whatever mode it's loaded in
+func (g *binaryGraph) checkOffsets() error {
+	ascending := func(at, count int, end uint32) bool {
+		prev := uint32(0)
+		for i := range count {
+			off := g.u32(at, i)
+			if off < prev || off > end {
+				return false
+			}
+			prev = off
+		}
+		return prev == end
+	}
+	if !ascending(g.strOffsAt, 2*g.nodes+1, g.header.StringBytes) {
+		return fmt.Errorf("string offsets out of range")
+	}
+	if !ascending(g.linkOffsAt, g.nodes+1, g.header.Edges) {
+		return fmt.Errorf("link offsets out of range")
+	}
+	for k := range int(g.header.Edges) {
+		if target := g.u32(g.edgesAt, k); int(target) >= g.nodes {
+			return fmt.Errorf("edge %d links to node %d, out of range", k, target)
+		}
+	}
+	return nil
+}
+
+// u32 reads the i-th uint32 of the section at at
+func (g *binaryGraph) u32(at, i int) uint32 {
+	return binary.LittleEndian.Uint32(g.data[at+4*i:])
+}
+
+// str returns string k of the string table, IDs are 0..nodes-1 and labels nodes..2*nodes-1
+// The bytes point into data, they're only good while g is
+func (g *binaryGraph) str(k int) []byte {
+	from, to := g.u32(g.strOffsAt, k), g.u32(g.strOffsAt, k+1)
+	return g.data[g.stringsAt+int(from) : g.stringsAt+int(to)]
+}
+
+// id returns node i's ID as a string of its own, safe to keep after the mapping is gone
+func (g *binaryGraph) id(i int) string {
+	id := string(g.str(i))
+	runtime.KeepAlive(g)
+	return id
+}
+
+// index finds the node with a neighbor list whose ID is id
+func (g *binaryGraph) index(id string) (int, bool) {
+	i := sort.Search(g.entries, func(i int) bool { return string(g.str(i)) >= id })
+	found := i < g.entries && string(g.str(i)) == id
+	runtime.KeepAlive(g)
+	return i, found
+}
+
+// links returns the node indexes node i links to, as offsets into the edges section
+func (g *binaryGraph) links(i int) (from, to int) {
+	return int(g.u32(g.linkOffsAt, i)), int(g.u32(g.linkOffsAt, i+1))
+}
+
+func (g *binaryGraph) Len() int      { return g.entries }
+func (g *binaryGraph) NumEdges() int { return int(g.header.Edges) }
+
+func (g *binaryGraph) Has(id string) bool {
+	_, ok := g.index(id)
+	return ok
+}
+
+func (g *binaryGraph) Neighbors(id string) []string {
+	i, ok := g.index(id)
+	if !ok {
+		return nil
+	}
+	from, to := g.links(i)
+	neighbors := make([]string, to-from)
+	for k := from; k < to; k++ {
+		neighbors[k-from] = g.id(int(g.u32(g.edgesAt, k)))
+	}
+	return neighbors
+}
+
+func (g *binaryGraph) Label(id string) string {
+	i, ok := g.index(id)
+	if !ok {
+		return ""
+	}
+	label := string(g.str(g.nodes + i))
+	runtime.KeepAlive(g)
+	return label
+}
+
+func (g *binaryGraph) IDs() []string {
+	ids := make([]string, g.entries)
+	for i := range ids {
+		ids[i] = g.id(i)
+	}
+	return ids
+}
+
+// people returns the people of a full article graph, nil when the file doesn't mark them
+func (g *binaryGraph) people() map[string]bool {
+	if g.header.Flags&flagPeople == 0 {
+		return nil
+	}
+	people := make(map[string]bool)
+	for i := range g.entries {
+		if g.data[g.flagsAt+i]&nodePerson != 0 {
+			people[g.id(i)] = true
+		}
+	}
+	return people
+}
+
+// envelope decodes the extra section into a graph file, its Graph and Labels are left for the caller
+func (g *binaryGraph) envelope() (*models.GraphFile, error) {
+	var extra binaryExtra
+	err := json.Unmarshal(g.data[g.extraAt:], &extra)
+	runtime.KeepAlive(g)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode graph metadata: %w", err)
+	}
+	return &models.GraphFile{
+		Version:   int(g.header.GraphVersion),
+		Meta:      extra.Meta,
+		Revisions: extra.Revisions,
+		Tags:      extra.Tags,
+		Aliases:   extra.Aliases,
+		Attrs:     extra.Attrs,
+		EdgeAttrs: extra.EdgeAttrs,
+	}, nil
+}
+
+// validate checks a binary graph for the problems Validate finds, without decoding it: the writer already
+// repaired the graph, so this only turns anything up in files written some other way
+func (g *binaryGraph) validate() *ValidationReport {
+	report := &ValidationReport{Issues: []GraphIssue{}, ByKind: make(map[string]int)}
+	add := func(kind string, i, to int) {
+		issue := GraphIssue{Kind: kind, Node: g.id(i)}
+		if to >= 0 {
+			issue.Neighbor = g.id(to)
+		}
+		report.Issues = append(report.Issues, issue)
+		report.ByKind[kind]++
+	}
+	for i := range g.entries {
+		if len(g.str(i)) == 0 {
+			add(IssueEmptyName, i, -1)
+		}
+		from, to := g.links(i)
+		prev := -1
+		for k := from; k < to; k++ {
+			target := int(g.u32(g.edgesAt, k))
+			switch {
+			case target == i:
+				add(IssueSelfLoop, i, target)
+			case target == prev:
+				add(IssueDuplicate, i, target) // Lists are sorted, a duplicate is next to its twin
+			case target >= g.entries:
+				add(IssueDangling, i, target)
+			}
+			prev = target
+		}
+	}
+	return report
+}
+
+// openBinaryGraph wraps a binary graph for searching without decoding it, see binaryGraph
+// release is called once nothing uses the graph anymore, e.g. to unmap data. A binary graph can't be repaired
+// in place, but its writer already did that, so only strict mode checks it (see Validate).
+func openBinaryGraph(data []byte, release func() error, mode ValidationMode) (*models.GraphFile, *NodeGraph, map[string]bool, error) {
+	bg, err := parseBinaryGraph(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if mode == Strict {
+		if report := bg.validate(); len(report.Issues) > 0 {
+			return nil, nil, nil, fmt.Errorf("graph has %d problems: %s", len(report.Issues), report.Summary())
+		}
+	}
+	file, err := bg.envelope()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	runtime.AddCleanup(bg, func(release func() error) { release() }, release)
+	g := &NodeGraph{adj: bg, attrs: file.Attrs, edgeAttrs: file.EdgeAttrs}
+	return file, g, bg.people(), nil
+}
+
+// mapBinaryGraph maps a binary graph file into memory and opens it there (see openBinaryGraph), the mapping goes
+// away with the graph
+func mapBinaryGraph(filename string, mode ValidationMode) (*models.GraphFile, *NodeGraph, map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open graph file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open graph file: %w", err)
+	}
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to map %s: %w", filename, err)
+	}
+	file, g, people, err := openBinaryGraph(data, unmap, mode)
+	if err != nil {
+		unmap()
+		return nil, nil, nil, fmt.Errorf("graph file %s: %w", filename, err)
+	}
+	return file, g, people, nil
+}
+
+// LoadBinaryGraph reads a whole binary graph file into a graph file, plus the people of a full article graph (nil
+// when the file doesn't mark them). It's for tools that want the graph file, the server searches binary files in
+// place instead (see binaryGraph).
+func LoadBinaryGraph(filename string) (*models.GraphFile, map[string]bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open graph file: %w", err)
+	}
+	file, people, err := decodeBinaryGraph(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("graph file %s: %w", filename, err)
+	}
+	return file, people, nil
+}
+
+// decodeBinaryGraph checks and decodes a whole binary file, nothing returned points into data
+func decodeBinaryGraph(data []byte) (*models.GraphFile, map[string]bool, error) {
+	bg, err := parseBinaryGraph(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := bg.envelope()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// One copy of the string table, every ID and label is a substring of it
+	strs := string(data[bg.stringsAt : bg.stringsAt+int(bg.header.StringBytes)])
+	str := func(k int) string {
+		return strs[bg.u32(bg.strOffsAt, k):bg.u32(bg.strOffsAt, k+1)]
+	}
+
+	// All neighbor lists live in one slice, capped so an append on one never runs into the next
+	neighbors := make([]string, bg.header.Edges)
+	file.Graph = make(models.Graph, bg.entries)
+	for i := range bg.entries {
+		from, to := bg.links(i)
+		for k := from; k < to; k++ {
+			neighbors[k] = str(int(bg.u32(bg.edgesAt, k)))
+		}
+		id := str(i)
+		file.Graph[id] = neighbors[from:to:to]
+		if label := str(bg.nodes + i); label != "" {
+			if file.Labels == nil {
+				file.Labels = make(map[string]string)
+			}
+			file.Labels[id] = label
+		}
+	}
+	return file, bg.people(), nil
+}
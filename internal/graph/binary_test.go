@@ -0,0 +1,165 @@
+package graph
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// sameNodeGraph checks that two node graphs have the same nodes, labels, neighbors and attributes
+func sameNodeGraph(t *testing.T, got, want *NodeGraph) {
+	t.Helper()
+	if got.Len() != want.Len() || got.NumEdges() != want.NumEdges() {
+		t.Errorf("got %d nodes and %d edges, want %d and %d", got.Len(), got.NumEdges(), want.Len(), want.NumEdges())
+	}
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("nodes:\n%s\nwant:\n%s", gotJSON, wantJSON)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		people map[string]bool
+	}{
+		{"people graph", nil},
+		{"full article graph", map[string]bool{"Q1": true, "Q3": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "graph.bin")
+			file := attrFile()
+			if err := WriteBinaryGraph(path, file, tt.people); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(file, attrFile()) {
+				t.Errorf("WriteBinaryGraph changed the file it was given: %+v", file)
+			}
+
+			// Tools decode the whole file
+			loaded, people, err := LoadBinaryGraph(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, attrFile()) {
+				t.Errorf("decoded %+v\nwant %+v", loaded, attrFile())
+			}
+			if !reflect.DeepEqual(people, tt.people) {
+				t.Errorf("people = %v, want %v", people, tt.people)
+			}
+
+			// The server searches it where it's mapped
+			for _, mode := range []ValidationMode{Lenient, Strict} {
+				envelope, g, people, err := mapBinaryGraph(path, mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sameNodeGraph(t, g, NewNodeGraph(attrFile()))
+				if g.Has("Q5") || g.Neighbors("Q5") != nil || g.Label("Q5") != "" {
+					t.Error("a node that isn't in the file was found")
+				}
+				if !reflect.DeepEqual(envelope.Aliases, attrFile().Aliases) || !reflect.DeepEqual(envelope.Tags, attrFile().Tags) {
+					t.Errorf("envelope = %+v", envelope)
+				}
+				if !reflect.DeepEqual(people, tt.people) {
+					t.Errorf("people = %v, want %v", people, tt.people)
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryRepairsOnWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.bin")
+	file := &models.GraphFile{
+		Version: models.GraphFileVersion,
+		Graph:   models.Graph{"A": {"B", "A", "B", "Z"}, "B": nil, "C": {"A"}},
+	}
+	if err := WriteBinaryGraph(path, file, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.Graph{"A": {"B", "A", "B", "Z"}, "B": nil, "C": {"A"}}); !reflect.DeepEqual(file.Graph, want) {
+		t.Errorf("WriteBinaryGraph changed the graph it was given: %v", file.Graph)
+	}
+
+	// The self-loop, the duplicate and the dangling edge are gone, so a strict load takes it
+	_, g, _, err := mapBinaryGraph(path, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"A": {"B"}, "B": {}, "C": {"A"}}
+	if ids := g.IDs(); !reflect.DeepEqual(ids, []string{"A", "B", "C"}) {
+		t.Errorf("IDs = %v", ids)
+	}
+	for id, neighbors := range want {
+		if got := g.Neighbors(id); !reflect.DeepEqual(got, neighbors) {
+			t.Errorf("neighbors of %s = %v, want %v", id, got, neighbors)
+		}
+	}
+}
+
+func TestBinaryDamagedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.bin")
+	if err := WriteBinaryGraph(path, attrFile(), nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte(nil), data...)
+	flipped[binaryHeaderSize+5] ^= 0xff
+	// The reserved bytes at the end of the header aren't read, only the checksum notices them changing
+	header := append([]byte(nil), data...)
+	header[binaryHeaderSize-1] ^= 0xff
+	// An edge to a node that doesn't exist, with a checksum that matches: a file written wrong, not damaged
+	outOfRange := append([]byte(nil), data...)
+	bg, err := parseBinaryGraph(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(outOfRange[bg.edgesAt:], 99)
+	binary.LittleEndian.PutUint32(outOfRange[binaryChecksumAt:], binaryChecksum(outOfRange, outOfRange[binaryHeaderSize:]))
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"corrupted", flipped, "checksum mismatch"},
+		{"header corrupted", header, "checksum mismatch"},
+		{"edge out of range", outOfRange, "links to node 99, out of range"},
+		{"truncated", data[:len(data)-3], "truncated"},
+		{"too short", data[:10], "too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			damaged := filepath.Join(dir, tt.name+".bin")
+			if err := os.WriteFile(damaged, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := LoadBinaryGraph(damaged); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("LoadBinaryGraph: err = %v, want %q", err, tt.err)
+			}
+			if _, _, _, err := mapBinaryGraph(damaged, Lenient); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("mapBinaryGraph: err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
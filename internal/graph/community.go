@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"sort"
+)
+
+// Label propagation gives up after this many sweeps, it settles in a handful on graphs like ours
+const maxPropagationRounds = 20
+
+// Communities groups the nodes of g with label propagation, treating links as undirected
+// Every node starts in its own community and repeatedly joins the one most of its neighbors are in (ties go to
+// the lowest). Nodes are visited in ID order so the same graph always gives the same communities. Communities
+// are numbered by size, 0 is the biggest.
+func Communities(g *NodeGraph) map[string]int {
+	ids := g.IDs()
+	index := make(map[string]int32, len(ids))
+	for i, id := range ids {
+		index[id] = int32(i)
+	}
+
+	adj := make([][]int32, len(ids))
+	for i, id := range ids {
+		for _, to := range g.Neighbors(id) {
+			j, ok := index[to]
+			if !ok || j == int32(i) {
+				continue
+			}
+			adj[i] = append(adj[i], j)
+			adj[j] = append(adj[j], int32(i))
+		}
+	}
+
+	label := make([]int32, len(ids))
+	for i := range label {
+		label[i] = int32(i)
+	}
+	counts := make(map[int32]int)
+	for range maxPropagationRounds {
+		changed := false
+		for i, neighbors := range adj {
+			if len(neighbors) == 0 {
+				continue
+			}
+			clear(counts)
+			for _, j := range neighbors {
+				counts[label[j]]++
+			}
+			best := label[i]
+			for l, n := range counts {
+				if n > counts[best] || n == counts[best] && l < best {
+					best = l
+				}
+			}
+			if best != label[i] {
+				label[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Renumber by size, biggest first, ties by the community's first node in ID order
+	size := make(map[int32]int)
+	first := make(map[int32]int)
+	for i, l := range label {
+		if _, ok := first[l]; !ok {
+			first[l] = i
+		}
+		size[l]++
+	}
+	order := make([]int32, 0, len(size))
+	for l := range size {
+		order = append(order, l)
+	}
+	sort.Slice(order, func(a, b int) bool {
+		if size[order[a]] != size[order[b]] {
+			return size[order[a]] > size[order[b]]
+		}
+		return first[order[a]] < first[order[b]]
+	})
+	number := make(map[int32]int, len(order))
+	for n, l := range order {
+		number[l] = n
+	}
+
+	communities := make(map[string]int, len(ids))
+	for i, id := range ids {
+		communities[id] = number[label[i]]
+	}
+	return communities
+}
@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// The compact format holds graphs too big for one JSON document (the full article graph has millions of edges)
+// It's JSON lines: a header, then one line per node in index order whose links point at other nodes by index.
+// Both sides stream it, so neither the fetcher nor the server ever holds the encoded file in memory.
+// It's keyed by title for good (version 1), most of its nodes are articles without a QID, so loading it never
+// migrates it to node IDs.
+//
+//	{"version":1,"format":"compact","meta":{...},"nodes":3}
+//	{"n":"Albert Einstein","p":true,"l":[1]}
+//	{"n":"Princeton University","l":[0,2]}
+//	{"n":"J. Robert Oppenheimer","p":true,"l":[1]}
+
+// CompactGraph is a graph with nodes numbered 0..n-1, the shape the crawler builds and the compact format stores
+type CompactGraph struct {
+	Names  []string  // Article title of each node
+	People []bool    // Whether each node is a person (a seed), only people can start or end a search
+	Links  [][]int32 // Outgoing links of each node, by index
+}
+
+// compactHeader is the first line of a compact file
+type compactHeader struct {
+	Version int              `json:"version"`
+	Format  string           `json:"format"`
+	Meta    models.GraphMeta `json:"meta"`
+	Nodes   int              `json:"nodes"`
+}
+
+// compactNode is every line after the header
+type compactNode struct {
+	Name   string  `json:"n"`
+	Person bool    `json:"p,omitempty"`
+	Links  []int32 `json:"l"`
+}
+
+const compactFormat = "compact"
+
+// compactVersion is the envelope version compact files are written with, keyed by title
+const compactVersion = 1
+
+// Longest line we accept, a node with a few thousand links takes well under this
+const maxCompactLine = 16 << 20
+
+// WriteCompactGraph streams g to path atomically, filling in the node and edge counts of meta
+func WriteCompactGraph(path string, meta models.GraphMeta, g *CompactGraph) error {
+	meta.Nodes = len(g.Names)
+	meta.Edges = 0
+	for _, links := range g.Links {
+		meta.Edges += len(links)
+	}
+
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w) // Encode adds the newline that ends each line
+		header := compactHeader{Version: compactVersion, Format: compactFormat, Meta: meta, Nodes: len(g.Names)}
+		if err := enc.Encode(header); err != nil {
+			return err
+		}
+		for i, name := range g.Names {
+			node := compactNode{Name: name, Person: g.People[i], Links: g.Links[i]}
+			if node.Links == nil {
+				node.Links = []int32{}
+			}
+			if err := enc.Encode(node); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isCompactName reports whether filename is a compact file, gzipped or not
+func isCompactName(filename string) bool {
+	return path.Ext(strings.TrimSuffix(filename, gzipExt)) == ".jsonl"
+}
+
+// LoadCompactGraph reads a compact file into a graph file keyed by title, plus the set of people in it
+func LoadCompactGraph(filename string) (*models.GraphFile, map[string]bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open graph file: %w", err)
+	}
+	defer f.Close()
+	return readCompactGraph(filename, f)
+}
+
+// readCompactGraph is LoadCompactGraph for a compact file that's already open, filename only goes in errors
+func readCompactGraph(filename string, r io.Reader) (*models.GraphFile, map[string]bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxCompactLine)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		return nil, nil, fmt.Errorf("graph file %s is empty", filename)
+	}
+	var header compactHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != compactFormat {
+		return nil, nil, fmt.Errorf("graph file %s doesn't start with a compact header", filename)
+	}
+	if header.Version > models.GraphFileVersion {
+		return nil, nil, fmt.Errorf("graph file %s is version %d, this build reads up to %d", filename, header.Version, models.GraphFileVersion)
+	}
+
+	names := make([]string, 0, header.Nodes)
+	links := make([][]int32, 0, header.Nodes)
+	people := make(map[string]bool)
+	for line := 2; scanner.Scan(); line++ {
+		var node compactNode
+		if err := json.Unmarshal(scanner.Bytes(), &node); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode line %d of %s: %w", line, filename, err)
+		}
+		names = append(names, node.Name)
+		links = append(links, node.Links)
+		if node.Person {
+			people[node.Name] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if len(names) != header.Nodes {
+		return nil, nil, fmt.Errorf("graph file %s is truncated: header says %d nodes, found %d", filename, header.Nodes, len(names))
+	}
+
+	// Neighbor lists reuse the strings in names, so each title is only in memory once
+	graph := make(models.Graph, len(names))
+	for i, name := range names {
+		neighbors := make([]string, 0, len(links[i]))
+		for _, to := range links[i] {
+			if to < 0 || int(to) >= len(names) {
+				return nil, nil, fmt.Errorf("node %q in %s links to index %d, out of range", name, filename, to)
+			}
+			neighbors = append(neighbors, names[to])
+		}
+		graph[name] = neighbors
+	}
+
+	file := &models.GraphFile{Version: header.Version, Meta: header.Meta, Graph: graph}
+	return file, people, nil
+}
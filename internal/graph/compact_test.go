@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// princeton is a small full article graph: two people linked through a university
+func princeton() *CompactGraph {
+	return &CompactGraph{
+		Names:  []string{"Albert Einstein", "Princeton University", "J. Robert Oppenheimer"},
+		People: []bool{true, false, true},
+		Links:  [][]int32{{1}, {0, 2}, nil},
+	}
+}
+
+func TestCompactRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName(DefaultLang, VariantFull))
+	meta := models.GraphMeta{FetchedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), Source: "en.wikipedia.org"}
+	if err := WriteCompactGraph(path, meta, princeton()); err != nil {
+		t.Fatal(err)
+	}
+	// A nodes file with QIDs next to it mustn't rekey the graph, compact files stay keyed by title
+	nodes := map[string]models.Person{"Albert Einstein": {Name: "Albert Einstein", QID: "Q937"}}
+	if err := WriteNodes(filepath.Join(dir, NodesFileName(DefaultLang)), nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	file, people, err := LoadAnyGraph(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Nodes, meta.Edges = 3, 3
+	want := &models.GraphFile{
+		Version: compactVersion,
+		Meta:    meta,
+		Graph: models.Graph{
+			"Albert Einstein":       {"Princeton University"},
+			"Princeton University":  {"Albert Einstein", "J. Robert Oppenheimer"},
+			"J. Robert Oppenheimer": {},
+		},
+	}
+	if !reflect.DeepEqual(file, want) {
+		t.Errorf("loaded %+v\nwant %+v", file, want)
+	}
+	if want := map[string]bool{"Albert Einstein": true, "J. Robert Oppenheimer": true}; !reflect.DeepEqual(people, want) {
+		t.Errorf("people = %v, want %v", people, want)
+	}
+
+	// The header says what it is
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(string(data), "\n")
+	if !strings.HasPrefix(header, `{"version":1,"format":"compact",`) || !strings.HasSuffix(header, `"nodes":3}`) {
+		t.Errorf("header = %s", header)
+	}
+}
+
+func TestCompactDamagedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.en.full.jsonl")
+	if err := WriteCompactGraph(path, models.GraphMeta{}, princeton()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"empty", "", "is empty"},
+		{"no header", lines[1], "doesn't start with a compact header"},
+		{"truncated", strings.Join(lines[:3], ""), "header says 3 nodes, found 2"},
+		{"link out of range", strings.Join(lines[:3], "") + `{"n":"J. Robert Oppenheimer","p":true,"l":[7]}` + "\n", "links to index 7, out of range"},
+		{"newer version", strings.Replace(string(data), `"version":1`, `"version":99`, 1), "is version 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			damaged := filepath.Join(dir, "damaged.jsonl")
+			if err := os.WriteFile(damaged, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := LoadCompactGraph(damaged); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package graph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Graphs can be built into the server (cmd/search with -tags embedgraph), so a container is one file that
+// always has the graphs it was built with. WriteEmbedded writes the files that get embedded, LoadStoreFS reads
+// them back. They're the files LoadStore reads from a directory, gzipped.
+
+// gzipExt marks a gzipped file, graph.bin.gz holds graph.bin
+const gzipExt = ".gz"
+
+// WriteEmbedded writes a gzipped binary copy of every graph in dir to out, with their nodes, contexts and relations
+// files gzipped next to them. Snapshots stay out, only the graphs in dir itself are written. Whatever out held
+// from an earlier run is removed first so a graph that's gone from dir doesn't linger.
+func WriteEmbedded(dir, out string) error {
+	files, err := graphFiles(dir)
+	if err != nil {
+		return err
+	}
+	if !hasDefaultGraph(files) {
+		return fmt.Errorf("no %s found in %q", FileName(DefaultLang, ""), dir)
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	stale, err := filepath.Glob(filepath.Join(out, "*"+gzipExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	langs := make(map[string]bool)
+	for _, gf := range files {
+		file, people, err := LoadAnyGraph(gf.path)
+		if err != nil {
+			return err
+		}
+		name := BinaryFileName(gf.lang, gf.variant) + gzipExt
+		err = writeGzip(filepath.Join(out, name), func(w io.Writer) error {
+			return encodeBinaryGraph(w, file, people)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		log.Printf("%s -> %s: %d nodes, %d edges", gf.path, name, len(file.Graph), CountEdges(file.Graph))
+		langs[gf.lang] = true
+	}
+
+	for _, lang := range sortedKeys(langs) {
+		for _, name := range []string{NodesFileName(lang), ContextsFileName(lang), RelationsFileName(lang)} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			err = writeGzip(filepath.Join(out, name+gzipExt), func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", name+gzipExt, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeGzip writes a gzipped file atomically, write gets the uncompressed side
+func writeGzip(path string, write func(w io.Writer) error) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+		if err != nil {
+			return err
+		}
+		if err := write(zw); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// hasDefaultGraph reports whether the graph every store needs is among files
+func hasDefaultGraph(files []graphFile) bool {
+	for _, gf := range files {
+		if gf.lang == DefaultLang && gf.variant == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadStoreFS loads the graphs at the top of fsys as the DefaultSnapshot, like LoadStore does for a directory
+// Any file can be gzipped (graph.bin.gz reads as graph.bin), that's how WriteEmbedded leaves them. There are no
+// snapshots, and a dataset that's there in two formats is an error rather than a pick by modification time.
+func LoadStoreFS(fsys fs.FS, mode ValidationMode) (*Store, error) {
+	store := &Store{datasets: make(map[string]*Dataset), loadedAt: time.Now()}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), gzipExt)
+		lang, variant, ok := parseFileName(name)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, dup := store.datasets[storeKey(DefaultSnapshot, datasetName(lang, variant))]; dup {
+			return nil, fmt.Errorf("%s is there twice, keep one format of it", datasetName(lang, variant))
+		}
+
+		_, data, err := readFS(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var ds *Dataset
+		if isBinary(data) {
+			// Searched where it was decompressed to, like a binary file on disk is where it's mapped
+			file, g, people, err := openBinaryGraph(data, func() error { return nil }, mode)
+			if err != nil {
+				return nil, fmt.Errorf("graph file %s: %w", e.Name(), err)
+			}
+			ds = wrapDataset(e.Name(), lang, variant, file, g, people)
+		} else if ds, err = decodedDatasetFS(fsys, e.Name(), lang, variant, data, mode); err != nil {
+			return nil, err
+		}
+		if err := ds.loadSideFiles(func(name string) (string, []byte, error) { return readFS(fsys, name) }); err != nil {
+			return nil, err
+		}
+		store.add(DefaultSnapshot, ds)
+	}
+
+	if _, ok := store.datasets[storeKey(DefaultSnapshot, datasetName(DefaultLang, ""))]; !ok {
+		return nil, fmt.Errorf("no %s among the embedded graphs", FileName(DefaultLang, ""))
+	}
+	return store, nil
+}
+
+// decodedDatasetFS decodes a graph file from LoadStoreFS that isn't binary, migrating it like LoadAnyGraph does
+func decodedDatasetFS(fsys fs.FS, filename, lang, variant string, data []byte, mode ValidationMode) (*Dataset, error) {
+	file, people, err := decodeAnyGraph(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	if file.Version < 2 && !isCompactName(filename) {
+		var nodes map[string]models.Person
+		if path, data, err := readFS(fsys, NodesFileName(lang)); err == nil {
+			if nodes, err = decodeSideFile[map[string]models.Person]("nodes", path, data); err != nil {
+				return nil, err
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		log.Printf("Migrating %s from version %d (keyed by title) to node IDs", filename, file.Version)
+		AssignIDs(file, nodes)
+	}
+	return checkedDataset(filename, lang, variant, file, people, mode)
+}
+
+// readFS reads name from fsys, from name.gz when that's there, and returns the name it read
+func readFS(fsys fs.FS, name string) (string, []byte, error) {
+	data, err := fs.ReadFile(fsys, name+gzipExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = fs.ReadFile(fsys, name)
+		return name, data, err
+	}
+	if err != nil {
+		return name + gzipExt, nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return name + gzipExt, nil, fmt.Errorf("failed to decompress %s: %w", name+gzipExt, err)
+	}
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return name + gzipExt, nil, fmt.Errorf("failed to decompress %s: %w", name+gzipExt, err)
+	}
+	return name + gzipExt, data, nil
+}
+
+// isBinary reports whether data starts with the binary format's magic bytes
+func isBinary(data []byte) bool {
+	return len(data) >= len(binaryMagic) && bytes.Equal(data[:len(binaryMagic)], binaryMagic[:])
+}
+
+// decodeAnyGraph is LoadAnyGraph for a graph file that's in memory, the format comes from the magic bytes or
+// the name (minus any .gz) like it does on disk. Title keyed files come back as they are, unmigrated.
+func decodeAnyGraph(filename string, data []byte) (*models.GraphFile, map[string]bool, error) {
+	switch {
+	case isBinary(data):
+		file, people, err := decodeBinaryGraph(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("graph file %s: %w", filename, err)
+		}
+		return file, people, nil
+	case isCompactName(filename):
+		return readCompactGraph(filename, bytes.NewReader(data))
+	default:
+		file, err := decodeGraphFile(filename, data)
+		return file, nil, err
+	}
+}
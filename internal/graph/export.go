@@ -0,0 +1,413 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Export formats, for Gephi, Cytoscape, networkx and Graphviz
+// CSV comes as two files, nodes with their attributes and the edge list (source,target)
+const (
+	FormatGraphML  = "graphml"
+	FormatGEXF     = "gexf"
+	FormatDOT      = "dot"
+	FormatNodesCSV = "nodes-csv"
+	FormatEdgesCSV = "edges-csv"
+)
+
+// ExportFormats lists every format Export writes
+var ExportFormats = []string{FormatGraphML, FormatGEXF, FormatDOT, FormatNodesCSV, FormatEdgesCSV}
+
+// ExportExt is the file extension that goes with a format
+func ExportExt(format string) string {
+	switch format {
+	case FormatNodesCSV:
+		return ".nodes.csv"
+	case FormatEdgesCSV:
+		return ".edges.csv"
+	}
+	return "." + format
+}
+
+// ExportContentType is the media type to serve a format with
+func ExportContentType(format string) string {
+	switch format {
+	case FormatGraphML:
+		return "application/graphml+xml"
+	case FormatGEXF:
+		return "application/gexf+xml"
+	case FormatDOT:
+		return "text/vnd.graphviz"
+	}
+	return "text/csv"
+}
+
+// exportNode is a node with every attribute an export carries
+type exportNode struct {
+	id          string
+	label       string
+	inDegree    int
+	outDegree   int
+	community   int
+	tags        string // Joined with ";"
+	description string
+	person      bool
+	attrs       map[string]string // The node's own attributes (models.Node.Attrs)
+	neighbors   []string
+}
+
+// exportAttr is a node attribute as the formats declare it
+type exportAttr struct {
+	name string
+	typ  string // GraphML type: string, int, double or boolean
+	get  func(n *exportNode) string
+}
+
+// edgeAttr is an edge attribute as the formats declare it
+type edgeAttr struct {
+	name string
+	typ  string
+	get  func(e *models.Edge) string
+}
+
+// exportAttrs lists the node attributes: the ones every export has, then the nodes' own attributes by name
+// person is only there for graphs where not every node is one
+func (ds *Dataset) exportAttrs() []exportAttr {
+	attrs := []exportAttr{
+		{"label", "string", func(n *exportNode) string { return n.label }},
+		{"in_degree", "int", func(n *exportNode) string { return strconv.Itoa(n.inDegree) }},
+		{"out_degree", "int", func(n *exportNode) string { return strconv.Itoa(n.outDegree) }},
+		{"community", "int", func(n *exportNode) string { return strconv.Itoa(n.community) }},
+		{"tags", "string", func(n *exportNode) string { return n.tags }},
+		{"description", "string", func(n *exportNode) string { return n.description }},
+	}
+	if ds.People != nil {
+		attrs = append(attrs, exportAttr{"person", "boolean", func(n *exportNode) string { return strconv.FormatBool(n.person) }})
+	}
+
+	ours := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		ours[a.name] = true
+	}
+	for _, name := range ds.Graph.attrNames() {
+		if ours[name] {
+			continue // Ours win, an imported "label" column is the label already
+		}
+		attrs = append(attrs, exportAttr{name, "string", func(n *exportNode) string { return n.attrs[name] }})
+	}
+	return attrs
+}
+
+// exportEdgeAttrs lists the edge attributes any edge of the graph has, none for a plain link graph
+func (ds *Dataset) exportEdgeAttrs() []edgeAttr {
+	all := []edgeAttr{
+		{"weight", "double", func(e *models.Edge) string {
+			if e.Weight == 0 {
+				return ""
+			}
+			return strconv.FormatFloat(e.Weight, 'g', -1, 64)
+		}},
+		{"type", "string", func(e *models.Edge) string { return e.Type }},
+		{"provenance", "string", func(e *models.Edge) string { return e.Provenance }},
+	}
+	// Only edges with attributes are in the side map, a plain link graph has nothing to go through
+	var attrs []edgeAttr
+	for _, a := range all {
+	search:
+		for _, edges := range ds.Graph.edgeAttrs {
+			for _, ea := range edges {
+				if a.get(&models.Edge{EdgeAttrs: ea}) != "" {
+					attrs = append(attrs, a)
+					break search
+				}
+			}
+		}
+	}
+	return attrs
+}
+
+// exportNodes lists the nodes in ID order with their attributes
+func (ds *Dataset) exportNodes() []exportNode {
+	ids := ds.Graph.IDs()
+
+	inDegree := make(map[string]int, len(ids))
+	for _, id := range ids {
+		for _, to := range ds.Graph.Neighbors(id) {
+			inDegree[to]++
+		}
+	}
+	communities := ds.Communities()
+
+	nodes := make([]exportNode, len(ids))
+	for i, id := range ids {
+		neighbors := ds.Graph.Neighbors(id)
+		nodes[i] = exportNode{
+			id:          id,
+			label:       ds.Label(id),
+			inDegree:    inDegree[id],
+			outDegree:   len(neighbors),
+			community:   communities[id],
+			tags:        strings.Join(ds.Tags[id], ";"),
+			description: ds.Nodes[id].Description,
+			person:      ds.IsPerson(id),
+			attrs:       ds.Graph.Attrs(id),
+			neighbors:   neighbors,
+		}
+	}
+	return nodes
+}
+
+// edge builds the edge from -> to with its attributes, for the writers to go through one at a time
+func (ds *Dataset) edge(from, to string) *models.Edge {
+	return &models.Edge{To: to, EdgeAttrs: ds.Graph.EdgeAttrs(from, to)}
+}
+
+// Export writes the dataset in one of ExportFormats, streaming it so a big graph never sits in memory encoded
+// The writers don't check each Fprintf, bufio.Writer keeps the first error and Flush returns it
+func Export(w io.Writer, ds *Dataset, format string) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case FormatGraphML:
+		err = ds.writeGraphML(bw)
+	case FormatGEXF:
+		err = ds.writeGEXF(bw)
+	case FormatDOT:
+		err = ds.writeDOT(bw)
+	case FormatNodesCSV:
+		err = ds.writeNodesCSV(bw)
+	case FormatEdgesCSV:
+		err = ds.writeEdgesCSV(bw)
+	default:
+		return fmt.Errorf("unknown export format %q, use one of %s", format, strings.Join(ExportFormats, ", "))
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteExport exports the dataset to a file atomically
+func WriteExport(path string, ds *Dataset, format string) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		return Export(w, ds, format)
+	})
+}
+
+// xmlEscape escapes text for an XML attribute or element
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeGraphML writes the GraphML document, node attributes as <data> keyed by attribute name
+func (ds *Dataset) writeGraphML(w *bufio.Writer) error {
+	attrs := ds.exportAttrs()
+	fmt.Fprint(w, xml.Header)
+	fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	for _, a := range attrs {
+		fmt.Fprintf(w, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=%q/>\n", xmlEscape(a.name), xmlEscape(a.name), a.typ)
+	}
+	edgeAttrs := ds.exportEdgeAttrs()
+	for _, a := range edgeAttrs {
+		fmt.Fprintf(w, "  <key id=\"e_%s\" for=\"edge\" attr.name=%q attr.type=%q/>\n", a.name, a.name, a.typ)
+	}
+	fmt.Fprintf(w, "  <graph id=\"%s\" edgedefault=\"directed\">\n", xmlEscape(ds.Name))
+	nodes := ds.exportNodes()
+	for i := range nodes {
+		fmt.Fprintf(w, "    <node id=\"%s\">\n", xmlEscape(nodes[i].id))
+		for _, a := range attrs {
+			if v := a.get(&nodes[i]); v != "" {
+				fmt.Fprintf(w, "      <data key=\"%s\">%s</data>\n", xmlEscape(a.name), xmlEscape(v))
+			}
+		}
+		fmt.Fprintln(w, "    </node>")
+	}
+	edge := 0
+	for i := range nodes {
+		for _, to := range nodes[i].neighbors {
+			e := ds.edge(nodes[i].id, to)
+			fmt.Fprintf(w, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\"", edge, xmlEscape(nodes[i].id), xmlEscape(e.To))
+			edge++
+			var data []string
+			for _, a := range edgeAttrs {
+				if v := a.get(e); v != "" {
+					data = append(data, fmt.Sprintf("<data key=\"e_%s\">%s</data>", a.name, xmlEscape(v)))
+				}
+			}
+			if len(data) == 0 {
+				fmt.Fprintln(w, "/>")
+				continue
+			}
+			fmt.Fprintf(w, ">%s</edge>\n", strings.Join(data, ""))
+		}
+	}
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</graphml>")
+	return nil
+}
+
+// writeGEXF writes a GEXF 1.3 document, the label is GEXF's own and the rest are declared attributes
+func (ds *Dataset) writeGEXF(w *bufio.Writer) error {
+	attrs := ds.exportAttrs()[1:] // label has its own attribute in GEXF
+	fmt.Fprint(w, xml.Header)
+	fmt.Fprintln(w, `<gexf xmlns="http://gexf.net/1.3" version="1.3">`)
+	fmt.Fprintf(w, "  <meta><creator>sixth_degree</creator><description>%s graph</description></meta>\n", xmlEscape(ds.Name))
+	fmt.Fprintln(w, `  <graph mode="static" defaultedgetype="directed">`)
+	fmt.Fprintln(w, `    <attributes class="node">`)
+	for i, a := range attrs {
+		typ := a.typ
+		if typ == "int" {
+			typ = "integer"
+		}
+		fmt.Fprintf(w, "      <attribute id=\"%d\" title=\"%s\" type=%q/>\n", i, xmlEscape(a.name), typ)
+	}
+	fmt.Fprintln(w, "    </attributes>")
+	// weight is GEXF's own edge attribute, the rest get declared
+	var edgeAttrs []edgeAttr
+	for _, a := range ds.exportEdgeAttrs() {
+		if a.name != "weight" {
+			edgeAttrs = append(edgeAttrs, a)
+		}
+	}
+	if len(edgeAttrs) > 0 {
+		fmt.Fprintln(w, `    <attributes class="edge">`)
+		for i, a := range edgeAttrs {
+			fmt.Fprintf(w, "      <attribute id=\"e%d\" title=%q type=%q/>\n", i, a.name, a.typ)
+		}
+		fmt.Fprintln(w, "    </attributes>")
+	}
+	fmt.Fprintln(w, "    <nodes>")
+	nodes := ds.exportNodes()
+	for i := range nodes {
+		fmt.Fprintf(w, "      <node id=\"%s\" label=\"%s\">\n        <attvalues>\n", xmlEscape(nodes[i].id), xmlEscape(nodes[i].label))
+		for k, a := range attrs {
+			if v := a.get(&nodes[i]); v != "" {
+				fmt.Fprintf(w, "          <attvalue for=\"%d\" value=\"%s\"/>\n", k, xmlEscape(v))
+			}
+		}
+		fmt.Fprintln(w, "        </attvalues>\n      </node>")
+	}
+	fmt.Fprintln(w, "    </nodes>")
+	fmt.Fprintln(w, "    <edges>")
+	edge := 0
+	for i := range nodes {
+		for _, to := range nodes[i].neighbors {
+			e := ds.edge(nodes[i].id, to)
+			fmt.Fprintf(w, "      <edge id=\"%d\" source=\"%s\" target=\"%s\"", edge, xmlEscape(nodes[i].id), xmlEscape(e.To))
+			edge++
+			if e.Weight != 0 {
+				fmt.Fprintf(w, " weight=\"%s\"", strconv.FormatFloat(e.Weight, 'g', -1, 64))
+			}
+			var values []string
+			for j, a := range edgeAttrs {
+				if v := a.get(e); v != "" {
+					values = append(values, fmt.Sprintf("<attvalue for=\"e%d\" value=\"%s\"/>", j, xmlEscape(v)))
+				}
+			}
+			if len(values) == 0 {
+				fmt.Fprintln(w, "/>")
+				continue
+			}
+			fmt.Fprintf(w, "><attvalues>%s</attvalues></edge>\n", strings.Join(values, ""))
+		}
+	}
+	fmt.Fprintln(w, "    </edges>")
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</gexf>")
+	return nil
+}
+
+// dotQuote quotes an ID or attribute value for Graphviz
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// writeDOT writes a Graphviz digraph, attributes go on each node and edge statement
+func (ds *Dataset) writeDOT(w *bufio.Writer) error {
+	attrs := ds.exportAttrs()
+	edgeAttrs := ds.exportEdgeAttrs()
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(ds.Name))
+	nodes := ds.exportNodes()
+	for i := range nodes {
+		var parts []string
+		for _, a := range attrs {
+			if v := a.get(&nodes[i]); v != "" {
+				parts = append(parts, dotQuote(a.name)+"="+dotQuote(v))
+			}
+		}
+		fmt.Fprintf(w, "  %s [%s];\n", dotQuote(nodes[i].id), strings.Join(parts, ", "))
+	}
+	for i := range nodes {
+		for _, to := range nodes[i].neighbors {
+			e := ds.edge(nodes[i].id, to)
+			var parts []string
+			for _, a := range edgeAttrs {
+				if v := a.get(e); v != "" {
+					parts = append(parts, a.name+"="+dotQuote(v))
+				}
+			}
+			if len(parts) == 0 {
+				fmt.Fprintf(w, "  %s -> %s;\n", dotQuote(nodes[i].id), dotQuote(e.To))
+				continue
+			}
+			fmt.Fprintf(w, "  %s -> %s [%s];\n", dotQuote(nodes[i].id), dotQuote(e.To), strings.Join(parts, ", "))
+		}
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// writeNodesCSV writes one row per node: id then every attribute
+func (ds *Dataset) writeNodesCSV(w *bufio.Writer) error {
+	attrs := ds.exportAttrs()
+	cw := csv.NewWriter(w)
+	header := []string{"id"}
+	for _, a := range attrs {
+		header = append(header, a.name)
+	}
+	cw.Write(header)
+	nodes := ds.exportNodes()
+	row := make([]string, len(header))
+	for i := range nodes {
+		row[0] = nodes[i].id
+		for k, a := range attrs {
+			row[k+1] = a.get(&nodes[i])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeEdgesCSV writes the edge list, one source,target row per edge plus the edge attributes the graph has
+func (ds *Dataset) writeEdgesCSV(w *bufio.Writer) error {
+	edgeAttrs := ds.exportEdgeAttrs()
+	cw := csv.NewWriter(w)
+	header := []string{"source", "target"}
+	for _, a := range edgeAttrs {
+		header = append(header, a.name)
+	}
+	cw.Write(header)
+	row := make([]string, len(header))
+	for _, id := range ds.Graph.IDs() {
+		for _, to := range ds.Graph.Neighbors(id) {
+			e := ds.edge(id, to)
+			row[0], row[1] = id, e.To
+			for j, a := range edgeAttrs {
+				row[j+2] = a.get(e)
+			}
+			cw.Write(row)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// langevinDataset has two Curies, a label that needs escaping in every format and a pair off on its own
+func langevinDataset(t *testing.T) *Dataset {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"graph.json": `{"version":2,
+			"graph":{"Q1":["Q2"],"Q2":["Q1"],"Q3":["Q1"],"Q4":["Q5"],"Q5":["Q4"]},
+			"labels":{"Q1":"Marie Curie","Q2":"Pierre Curie","Q3":"Paul \"Langevin\" & co","Q4":"Orville Wright","Q5":"Wilbur Wright"},
+			"tags":{"Q1":["nobel_laureate","physicist"]}}`,
+		"nodes.json": `{"Q1":{"name":"Marie Curie","description":"Polish-French physicist"}}`,
+	})
+	ds, err := LoadDataset(filepath.Join(dir, "graph.json"), Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestExportCSV(t *testing.T) {
+	ds := langevinDataset(t)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatNodesCSV, `id,label,in_degree,out_degree,community,tags,description
+Q1,Marie Curie,2,1,0,nobel_laureate;physicist,Polish-French physicist
+Q2,Pierre Curie,1,1,0,,
+Q3,"Paul ""Langevin"" & co",0,1,0,,
+Q4,Orville Wright,1,1,1,,
+Q5,Wilbur Wright,1,1,1,,
+`},
+		{FormatEdgesCSV, "source,target\nQ1,Q2\nQ2,Q1\nQ3,Q1\nQ4,Q5\nQ5,Q4\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Export(&buf, ds, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+}
+
+// The XML formats have to parse, and the label has to come back out the way it went in
+func TestExportXML(t *testing.T) {
+	ds := langevinDataset(t)
+	for _, format := range []string{FormatGraphML, FormatGEXF} {
+		var buf bytes.Buffer
+		if err := Export(&buf, ds, format); err != nil {
+			t.Fatal(err)
+		}
+		var nodes, edges int
+		found := false
+		dec := xml.NewDecoder(&buf)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s doesn't parse: %v", format, err)
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				switch tok.Name.Local {
+				case "node":
+					nodes++
+				case "edge":
+					edges++
+				}
+				for _, a := range tok.Attr {
+					found = found || a.Name.Local == "label" && a.Value == `Paul "Langevin" & co`
+				}
+			case xml.CharData:
+				found = found || string(tok) == `Paul "Langevin" & co`
+			}
+		}
+		if nodes != 5 || edges != 5 || !found {
+			t.Errorf("%s has %d nodes and %d edges, found the escaped label: %v", format, nodes, edges, found)
+		}
+	}
+}
+
+func TestExportDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, langevinDataset(t), FormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{`digraph "en" {`, `"Q3" [`, `="Paul \"Langevin\" & co"`, `"Q3" -> "Q1";`, "\n}\n"} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output has no %s:\n%s", want, dot)
+		}
+	}
+	if err := Export(&buf, langevinDataset(t), "svg"); err == nil || !strings.Contains(err.Error(), `unknown export format "svg"`) {
+		t.Errorf("err = %v", err)
+	}
+}
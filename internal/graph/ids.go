@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// AssignIDs rekeys a title keyed graph file (version 0 or 1, or a fresh fetch) by stable node IDs
+// A person's ID is their Wikidata QID when nodes knows it and their title otherwise, so the same
+// person keeps the same key when Wikipedia renames their article. Titles move to Labels, and the
+// names that led to the same ID (a seed name that now redirects, duplicate seeds) go to Aliases.
+func AssignIDs(file *models.GraphFile, nodes map[string]models.Person) {
+	if file.Version >= 2 {
+		return
+	}
+
+	idFor := func(title string) string {
+		if p, ok := nodes[title]; ok && p.QID != "" {
+			return p.QID
+		}
+		return title
+	}
+
+	labels := make(map[string]string)
+	aliases := make(map[string]string)
+
+	// Sorted so two titles sharing an ID always pick the same label
+	titles := make([]string, 0, len(file.Graph))
+	for title := range file.Graph {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	g := make(models.Graph, len(file.Graph))
+	for _, title := range titles {
+		id := idFor(title)
+		neighbors := make([]string, 0, len(file.Graph[title]))
+		for _, n := range file.Graph[title] {
+			if to := idFor(n); to != id {
+				neighbors = append(neighbors, to)
+			}
+		}
+
+		// Show the current article title, a seed name that now redirects is only an alias
+		label := title
+		if p := nodes[title]; p.Title != "" {
+			label = p.Title
+		}
+		if label != title && title != id {
+			aliases[title] = id
+		}
+
+		if _, dup := g[id]; dup {
+			// Two seeds for one person, the second name becomes an alias and the links get merged
+			if title != id {
+				aliases[title] = id
+			}
+			g[id] = append(g[id], neighbors...)
+			if nodes[title].Title == "" && id != title {
+				labels[id] = title
+			}
+			continue
+		}
+		g[id] = neighbors
+		if id != label {
+			labels[id] = label
+		}
+	}
+	// A label is never also an alias
+	for id, label := range labels {
+		if aliases[label] == id {
+			delete(aliases, label)
+		}
+	}
+	Normalize(g)
+
+	rekey := func(byTitle map[string]int64) map[string]int64 {
+		if byTitle == nil {
+			return nil
+		}
+		byID := make(map[string]int64, len(byTitle))
+		for title, v := range byTitle {
+			byID[idFor(title)] = v
+		}
+		return byID
+	}
+	if file.Tags != nil {
+		tags := make(models.Tags, len(file.Tags))
+		for title, t := range file.Tags {
+			tags[idFor(title)] = t
+		}
+		file.Tags = tags
+	}
+
+	// An alias that's also the label or ID of another node would shadow it
+	named := make(map[string]string, len(g))
+	for id := range g {
+		named[id] = id
+	}
+	for id, label := range labels {
+		named[label] = id
+	}
+	for alias, id := range aliases {
+		if other, ok := named[alias]; ok && other != id {
+			delete(aliases, alias)
+		}
+	}
+
+	file.Graph = g
+	file.Revisions = rekey(file.Revisions)
+	file.Labels = labels
+	file.Aliases = aliases
+	if len(file.Aliases) == 0 {
+		file.Aliases = nil
+	}
+	file.Version = models.GraphFileVersion
+}
+
+// KeepAliases carries over the names an older build knew its nodes by
+// A node whose label changed since (the article got renamed) keeps answering to its old title
+func KeepAliases(file, prev *models.GraphFile) {
+	if prev == nil {
+		return
+	}
+	add := func(name, id string) {
+		if _, ok := file.Graph[id]; !ok || name == id || file.Labels[id] == name {
+			return
+		}
+		if file.Aliases == nil {
+			file.Aliases = make(map[string]string)
+		}
+		if _, taken := file.Aliases[name]; !taken {
+			file.Aliases[name] = id
+		}
+	}
+	for id, label := range prev.Labels {
+		add(label, id)
+	}
+	for alias, id := range prev.Aliases {
+		add(alias, id)
+	}
+}
+
+// TitleKeyed is the reverse of AssignIDs, it returns a copy of the file keyed by label again
+// Labels and Aliases are kept so the next build can carry the aliases over
+func TitleKeyed(file *models.GraphFile) *models.GraphFile {
+	label := func(id string) string {
+		if l, ok := file.Labels[id]; ok {
+			return l
+		}
+		return id
+	}
+
+	out := *file
+	out.Graph = make(models.Graph, len(file.Graph))
+	for id, neighbors := range file.Graph {
+		titles := make([]string, len(neighbors))
+		for i, n := range neighbors {
+			titles[i] = label(n)
+		}
+		out.Graph[label(id)] = titles
+	}
+	if file.Revisions != nil {
+		out.Revisions = make(map[string]int64, len(file.Revisions))
+		for id, rev := range file.Revisions {
+			out.Revisions[label(id)] = rev
+		}
+	}
+	if file.Tags != nil {
+		out.Tags = make(models.Tags, len(file.Tags))
+		for id, t := range file.Tags {
+			out.Tags[label(id)] = t
+		}
+	}
+	out.Attrs, out.EdgeAttrs = nil, nil // Version 1 has no attributes, and nothing that reads it uses them
+	out.Version = 1
+	return &out
+}
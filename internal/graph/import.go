@@ -0,0 +1,450 @@
+package graph
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Imports turn other networks (an org chart, a citation network, ...) into graph files the server reads like
+// any other. Node IDs are kept as they are, labels are what the UI shows and what searches can be typed as.
+// Other node columns become node attributes, and weight, type and provenance columns edge attributes.
+
+// edgeAttrNames are the columns (or GraphML keys) that go on edges
+var edgeAttrNames = map[string]bool{"weight": true, "type": true, "provenance": true}
+
+// derivedAttrs are the node columns cmd/export computes from the graph, reading them back would only go stale
+var derivedAttrs = map[string]bool{"in_degree": true, "out_degree": true, "community": true, "person": true}
+
+// ImportOptions says how to read an edge list
+type ImportOptions struct {
+	Comma      rune   // Field separator, ',' for CSV and '\t' for TSV
+	Source     string // Header of the source column, "source" when empty
+	Target     string // Header of the target column, "target" when empty
+	NoHeader   bool   // The first row is an edge already, the first two columns are source and target
+	Undirected bool   // Every edge goes both ways
+}
+
+// importer builds a graph file one node and edge at a time
+type importer struct {
+	graph     models.Graph
+	labels    map[string]string
+	tags      models.Tags
+	attrs     map[string]map[string]string
+	edgeAttrs map[string]map[string]models.EdgeAttrs
+	selfLoops int
+	skipped   int // Rows or edges with an empty end
+}
+
+func newImporter() *importer {
+	return &importer{
+		graph:     make(models.Graph),
+		labels:    make(map[string]string),
+		tags:      make(models.Tags),
+		attrs:     make(map[string]map[string]string),
+		edgeAttrs: make(map[string]map[string]models.EdgeAttrs),
+	}
+}
+
+// node adds a node with no links yet, a no-op for one that's there
+func (im *importer) node(id string) {
+	if _, ok := im.graph[id]; !ok {
+		im.graph[id] = []string{}
+	}
+}
+
+// attr sets a node attribute, empty values are left out
+func (im *importer) attr(id, name, value string) {
+	if value == "" {
+		return
+	}
+	if im.attrs[id] == nil {
+		im.attrs[id] = make(map[string]string)
+	}
+	im.attrs[id][name] = value
+}
+
+// edge adds a link, both ways for undirected input. Both ends become nodes so no edge dangles.
+func (im *importer) edge(from, to string, undirected bool, attrs models.EdgeAttrs) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if from == "" || to == "" {
+		im.skipped++
+		return
+	}
+	im.node(from)
+	im.node(to)
+	if from == to {
+		im.selfLoops++
+		return
+	}
+	im.link(from, to, attrs)
+	if undirected {
+		im.link(to, from, attrs)
+	}
+}
+
+// link appends one directed edge, a repeated edge keeps the attributes it was last seen with
+func (im *importer) link(from, to string, attrs models.EdgeAttrs) {
+	im.graph[from] = append(im.graph[from], to)
+	if attrs == (models.EdgeAttrs{}) {
+		return
+	}
+	if im.edgeAttrs[from] == nil {
+		im.edgeAttrs[from] = make(map[string]models.EdgeAttrs)
+	}
+	im.edgeAttrs[from][to] = attrs
+}
+
+// file wraps the graph in the current envelope so it's read as keyed by node ID, no migration
+func (im *importer) file(source string) *models.GraphFile {
+	if im.selfLoops > 0 || im.skipped > 0 {
+		log.Printf("Dropped %d self-loops and %d edges missing an end", im.selfLoops, im.skipped)
+	}
+	Normalize(im.graph)
+	file := &models.GraphFile{
+		Version: models.GraphFileVersion,
+		Meta:    models.GraphMeta{FetchedAt: time.Now().UTC(), Source: source},
+		Graph:   im.graph,
+	}
+	for id, label := range im.labels {
+		if label != "" && label != id {
+			if file.Labels == nil {
+				file.Labels = make(map[string]string)
+			}
+			file.Labels[id] = label
+		}
+	}
+	if len(im.tags) > 0 {
+		file.Tags = im.tags
+	}
+	if len(im.attrs) > 0 {
+		file.Attrs = im.attrs
+	}
+	if len(im.edgeAttrs) > 0 {
+		file.EdgeAttrs = im.edgeAttrs
+	}
+	return file
+}
+
+// setEdgeAttr sets one of edgeAttrNames from its text
+func setEdgeAttr(attrs *models.EdgeAttrs, name, value string) error {
+	switch name {
+	case "weight":
+		if value == "" {
+			return nil
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("weight %q isn't a number", value)
+		}
+		attrs.Weight = w
+	case "type":
+		attrs.Type = value
+	case "provenance":
+		attrs.Provenance = value
+	}
+	return nil
+}
+
+// ImportEdgeList reads a CSV or TSV edge list, one link per row
+// weight, type and provenance columns become edge attributes, other extra columns (a date, a note) are ignored
+func ImportEdgeList(r io.Reader, source string, opts ImportOptions) (*models.GraphFile, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = opts.Comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = opts.Comma == '\t' // TSV exports rarely quote, a stray " shouldn't fail the whole file
+	cr.TrimLeadingSpace = true
+
+	from, to := 0, 1
+	extra := make(map[int]string) // Column -> edge attribute
+	if !opts.NoHeader {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s is empty", source)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if from, err = column(header, opts.Source, "source"); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		if to, err = column(header, opts.Target, "target"); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		for i, h := range header {
+			name := strings.ToLower(strings.TrimSpace(h))
+			if edgeAttrNames[name] && i != from && i != to {
+				extra[i] = name
+			}
+		}
+	}
+
+	im := newImporter()
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if len(row) <= max(from, to) {
+			im.skipped++
+			continue
+		}
+		var attrs models.EdgeAttrs
+		for i, name := range extra {
+			if i >= len(row) {
+				continue
+			}
+			if err := setEdgeAttr(&attrs, name, strings.TrimSpace(row[i])); err != nil {
+				line, _ := cr.FieldPos(i)
+				return nil, fmt.Errorf("%s line %d: %w", source, line, err)
+			}
+		}
+		im.edge(row[from], row[to], opts.Undirected, attrs)
+	}
+	return im.file(source), nil
+}
+
+// column finds a header's index, case and surrounding space don't matter
+func column(header []string, name, fallback string) (int, error) {
+	if name == "" {
+		name = fallback
+	}
+	i := slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), name) })
+	if i < 0 {
+		return 0, fmt.Errorf("no %q column in the header %v", name, header)
+	}
+	return i, nil
+}
+
+// ImportLabels reads a node file into a graph file: an id column plus a label (or name) column and optionally
+// tags separated by ";", the layout cmd/export writes. Nodes with no links yet are added, other columns become
+// node attributes. Nodes that share a label get their ID added to it, "John Smith (Q1)", so the label still
+// names one node.
+func ImportLabels(file *models.GraphFile, r io.Reader, source string, comma rune) error {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = comma == '\t'
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	idCol, err := column(header, "id", "")
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	labelCol, err := column(header, "label", "")
+	if err != nil {
+		if labelCol, err = column(header, "name", ""); err != nil {
+			return fmt.Errorf("%s: no label or name column in the header %v", source, header)
+		}
+	}
+	tagsCol, err := column(header, "tags", "")
+	if err != nil {
+		tagsCol = -1
+	}
+	attrCols := make(map[int]string)
+	for i, h := range header {
+		name := strings.TrimSpace(h)
+		if i != idCol && i != labelCol && i != tagsCol && name != "" && !derivedAttrs[strings.ToLower(name)] {
+			attrCols[i] = name
+		}
+	}
+
+	if file.Labels == nil {
+		file.Labels = make(map[string]string)
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if len(row) <= max(idCol, labelCol) {
+			continue
+		}
+		id, label := strings.TrimSpace(row[idCol]), strings.TrimSpace(row[labelCol])
+		if id == "" {
+			continue
+		}
+		if _, ok := file.Graph[id]; !ok {
+			file.Graph[id] = []string{}
+		}
+		if label != "" && label != id {
+			file.Labels[id] = label
+		}
+		if tagsCol >= 0 && tagsCol < len(row) && row[tagsCol] != "" {
+			if file.Tags == nil {
+				file.Tags = make(models.Tags)
+			}
+			for _, tag := range strings.Split(row[tagsCol], ";") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					file.Tags[id] = append(file.Tags[id], tag)
+				}
+			}
+		}
+		for i, name := range attrCols {
+			if i >= len(row) || strings.TrimSpace(row[i]) == "" {
+				continue
+			}
+			if file.Attrs == nil {
+				file.Attrs = make(map[string]map[string]string)
+			}
+			if file.Attrs[id] == nil {
+				file.Attrs[id] = make(map[string]string)
+			}
+			file.Attrs[id][name] = strings.TrimSpace(row[i])
+		}
+	}
+	uses := make(map[string]int, len(file.Labels))
+	for _, label := range file.Labels {
+		uses[label]++
+	}
+	for id, label := range file.Labels {
+		if uses[label] > 1 {
+			file.Labels[id] = disambiguate(label, id)
+		}
+	}
+	if len(file.Labels) == 0 {
+		file.Labels = nil
+	}
+	return nil
+}
+
+// ImportGraphML reads a GraphML document. A node's label comes from its "label" or "name" data and tags from
+// "tags" (separated by ";"), any other node data becomes a node attribute, and weight, type and provenance data on
+// edges edge attributes, so a file cmd/export wrote comes back the way it was. Edges follow the graph's
+// edgedefault and their own directed attribute, undirected forces both ways for all of them.
+func ImportGraphML(r io.Reader, source string, undirected bool) (*models.GraphFile, error) {
+	dec := xml.NewDecoder(r)
+	im := newImporter()
+	nodeKeys := make(map[string]string) // Key ID -> attribute name, for the keys we read
+	edgeKeys := make(map[string]string)
+	graphUndirected := false
+	node := "" // Node whose <data> we're in
+
+	// Edge whose <data> we're in, added once it ends
+	var edge struct {
+		from, to string
+		both     bool
+		attrs    models.EdgeAttrs
+		open     bool
+	}
+
+	attr := func(el xml.StartElement, name string) (string, bool) {
+		for _, a := range el.Attr {
+			if a.Name.Local == name {
+				return a.Value, true
+			}
+		}
+		return "", false
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", source, err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "key":
+				id, _ := attr(el, "id")
+				name, _ := attr(el, "attr.name")
+				if name == "" {
+					name = id
+				}
+				target, _ := attr(el, "for")
+				if target == "node" || target == "all" {
+					nodeKeys[id] = name
+				}
+				if (target == "edge" || target == "all") && edgeAttrNames[strings.ToLower(name)] {
+					edgeKeys[id] = strings.ToLower(name)
+				}
+			case "graph":
+				def, _ := attr(el, "edgedefault")
+				graphUndirected = def == "undirected"
+			case "node":
+				id, _ := attr(el, "id")
+				if id = strings.TrimSpace(id); id != "" {
+					im.node(id)
+					node = id
+				}
+			case "data":
+				if node == "" && !edge.open {
+					continue // Graph data
+				}
+				key, _ := attr(el, "key")
+				var data struct {
+					Value string `xml:",chardata"`
+				}
+				if err := dec.DecodeElement(&data, &el); err != nil {
+					return nil, fmt.Errorf("failed to decode %s: %w", source, err)
+				}
+				value := strings.TrimSpace(data.Value)
+				if edge.open {
+					if name, ok := edgeKeys[key]; ok {
+						if err := setEdgeAttr(&edge.attrs, name, value); err != nil {
+							return nil, fmt.Errorf("%s: edge %s -> %s: %w", source, edge.from, edge.to, err)
+						}
+					}
+					continue
+				}
+				name, ok := nodeKeys[key]
+				if !ok {
+					continue
+				}
+				switch strings.ToLower(name) {
+				case "label", "name":
+					im.labels[node] = value
+				case "tags":
+					for _, tag := range strings.Split(value, ";") {
+						if tag = strings.TrimSpace(tag); tag != "" {
+							im.tags[node] = append(im.tags[node], tag)
+						}
+					}
+				default:
+					if !derivedAttrs[strings.ToLower(name)] {
+						im.attr(node, name, value)
+					}
+				}
+			case "edge":
+				edge.from, _ = attr(el, "source")
+				edge.to, _ = attr(el, "target")
+				edge.both = undirected || graphUndirected
+				if directed, ok := attr(el, "directed"); ok {
+					edge.both = undirected || directed == "false"
+				}
+				edge.attrs = models.EdgeAttrs{}
+				edge.open = true
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "node":
+				node = ""
+			case "edge":
+				im.edge(edge.from, edge.to, edge.both, edge.attrs)
+				edge.open = false
+			}
+		}
+	}
+	return im.file(source), nil
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// export writes ds in one format
+func export(t *testing.T, ds *Dataset, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Export(&buf, ds, format); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// What an export and import carry over, revisions, aliases and meta stay behind
+func TestExportImportRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	if err := WriteGraphFile(path, attrFile()); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := LoadDataset(path, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		reload func(t *testing.T) (*models.GraphFile, error)
+	}{
+		{"graphml", func(t *testing.T) (*models.GraphFile, error) {
+			return ImportGraphML(export(t, ds, FormatGraphML), "graph.graphml", false)
+		}},
+		{"csv", func(t *testing.T) (*models.GraphFile, error) {
+			imported, err := ImportEdgeList(export(t, ds, FormatEdgesCSV), "graph.edges.csv", ImportOptions{Comma: ','})
+			if err != nil {
+				return nil, err
+			}
+			return imported, ImportLabels(imported, export(t, ds, FormatNodesCSV), "graph.nodes.csv", ',')
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.reload(t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := attrFile()
+			if got.Version != models.GraphFileVersion {
+				t.Errorf("version = %d, want %d", got.Version, models.GraphFileVersion)
+			}
+			if !reflect.DeepEqual(got.Graph, want.Graph) {
+				t.Errorf("graph = %v, want %v", got.Graph, want.Graph)
+			}
+			if !reflect.DeepEqual(got.Labels, want.Labels) {
+				t.Errorf("labels = %v, want %v", got.Labels, want.Labels)
+			}
+			if !reflect.DeepEqual(got.Tags, want.Tags) {
+				t.Errorf("tags = %v, want %v", got.Tags, want.Tags)
+			}
+			// in_degree, community and the rest are computed by the export and don't come back as attributes
+			if !reflect.DeepEqual(got.Attrs, want.Attrs) {
+				t.Errorf("attrs = %v, want %v", got.Attrs, want.Attrs)
+			}
+			if !reflect.DeepEqual(got.EdgeAttrs, want.EdgeAttrs) {
+				t.Errorf("edge attrs = %v, want %v", got.EdgeAttrs, want.EdgeAttrs)
+			}
+		})
+	}
+}
+
+func TestImportEdgeList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  ImportOptions
+		graph models.Graph
+		edges map[string]map[string]models.EdgeAttrs
+		err   string
+	}{
+		{
+			name:  "header with extra columns",
+			input: "from,to,weight,note\nA,B,1.5,met in 1900\nB,C,,\nA,A,,\nA,,,\n",
+			opts:  ImportOptions{Comma: ',', Source: "from", Target: "to"},
+			graph: models.Graph{"A": {"B"}, "B": {"C"}, "C": {}},
+			edges: map[string]map[string]models.EdgeAttrs{"A": {"B": {Weight: 1.5}}},
+		},
+		{
+			name:  "undirected tsv without a header",
+			input: "A\tB\nC\tB\n",
+			opts:  ImportOptions{Comma: '\t', NoHeader: true, Undirected: true},
+			graph: models.Graph{"A": {"B"}, "B": {"A", "C"}, "C": {"B"}},
+		},
+		{
+			name:  "bad weight",
+			input: "source,target,weight\nA,B,heavy\n",
+			opts:  ImportOptions{Comma: ','},
+			err:   `line 2: weight "heavy" isn't a number`,
+		},
+		{
+			name:  "no target column",
+			input: "source,dest\nA,B\n",
+			opts:  ImportOptions{Comma: ','},
+			err:   `no "target" column`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ImportEdgeList(strings.NewReader(tt.input), "edges.csv", tt.opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(file.Graph, tt.graph) {
+				t.Errorf("graph = %v, want %v", file.Graph, tt.graph)
+			}
+			if !reflect.DeepEqual(file.EdgeAttrs, tt.edges) {
+				t.Errorf("edge attrs = %v, want %v", file.EdgeAttrs, tt.edges)
+			}
+		})
+	}
+}
+
+// Two people called John Smith stay two people, each label names one of them
+func TestImportLabelsDuplicates(t *testing.T) {
+	file := &models.GraphFile{Graph: models.Graph{"Q1": {"Q2"}, "Q2": {"Q3"}}}
+	nodes := "id,label\nQ1,John Smith\nQ2,John Smith\nQ3,Pocahontas\n"
+	if err := ImportLabels(file, strings.NewReader(nodes), "nodes.csv", ','); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Q1": "John Smith (Q1)", "Q2": "John Smith (Q2)", "Q3": "Pocahontas"}
+	if !reflect.DeepEqual(file.Labels, want) {
+		t.Errorf("labels = %v, want %v", file.Labels, want)
+	}
+}
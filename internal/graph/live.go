@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Live is the store the server answers from, swapped for a fresh one when the graphs change
+// A reload loads and validates everything next to the current store and swaps it in at once: a search that
+// already picked its dataset finishes on the old graph, the next one gets the new graph. Anything built from a
+// dataset (people index, label graph) lives on the dataset so it swaps along with it.
+type Live struct {
+	dir   string
+	files fs.FS // Set for graphs that aren't on disk (see NewLiveFS), dir is unused then
+	mode  ValidationMode
+	store atomic.Pointer[Store]
+
+	mu   sync.Mutex // One reload at a time
+	seen string     // Fingerprint of the files the current store was loaded from
+}
+
+// NewLive loads the store for dir, failing like LoadStore does when there's nothing to serve
+func NewLive(dir string, mode ValidationMode) (*Live, error) {
+	l := &Live{dir: dir, mode: mode}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewLiveFS loads the store from the graphs at the top of fsys (see LoadStoreFS)
+// They can't change under a running server, so a reload only decodes them again and Watch has nothing to watch
+func NewLiveFS(fsys fs.FS, mode ValidationMode) (*Live, error) {
+	l := &Live{files: fsys, mode: mode}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Store returns the current store, hold on to it for the length of one request so it sees a single version
+func (l *Live) Store() *Store {
+	return l.store.Load()
+}
+
+// Reload loads the graphs from disk again and swaps them in, the current store stays when anything fails to load
+func (l *Live) Reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := time.Now()
+	if l.files != nil {
+		store, err := LoadStoreFS(l.files, l.mode)
+		if err != nil {
+			return err
+		}
+		l.store.Store(store)
+		log.Printf("Serving embedded graphs %v (loaded in %s)", store.Names(), time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+
+	fp, err := fingerprint(l.dir)
+	if err != nil {
+		return err
+	}
+	store, err := LoadStore(l.dir, l.mode)
+	if err != nil {
+		if l.store.Load() != nil {
+			log.Printf("Reload failed, still serving the graphs loaded %s: %v", l.store.Load().LoadedAt().Format(time.RFC3339), err)
+		}
+		return err
+	}
+	l.store.Store(store)
+	l.seen = fp
+	log.Printf("Serving graphs %v (loaded in %s)", store.Names(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// Watch checks the graph files every interval and reloads when they changed, until ctx is done
+// A change has to hold still for one interval first, so a fetch writing graph.json then nodes.json is
+// picked up once, after both are there. A set of files that failed to load isn't tried again until it changes.
+func (l *Live) Watch(ctx context.Context, interval time.Duration) {
+	if l.files != nil {
+		log.Printf("Not watching for changes, the graphs are embedded")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, tried := "", ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fp, err := fingerprint(l.dir)
+		if err != nil {
+			log.Printf("Couldn't check the graph files: %v", err)
+			continue
+		}
+		l.mu.Lock()
+		changed := fp != l.seen
+		l.mu.Unlock()
+		if changed && fp == last && fp != tried {
+			log.Printf("Graph files in %q changed, reloading", l.dir)
+			l.Reload() // Logs its own failure
+			tried = fp
+		}
+		last = fp
+	}
+}
+
+// fingerprint sums up the files LoadStore reads (name, size and modification time) so a change to any shows,
+// snapshots included
+func fingerprint(dir string) (string, error) {
+	snapshots, err := snapshotDirs(dir)
+	if err != nil {
+		return "", err
+	}
+	dirs := []string{dir}
+	for _, name := range sortedKeys(snapshots) {
+		dirs = append(dirs, snapshots[name])
+	}
+
+	var paths []string
+	for _, d := range dirs {
+		for _, pattern := range []string{"graph*.json", "graph*.jsonl", "graph*.bin", "nodes*.json", "contexts*.json", "relations*.json"} {
+			matches, err := filepath.Glob(filepath.Join(d, pattern))
+			if err != nil {
+				return "", err
+			}
+			paths = append(paths, matches...)
+		}
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue // Renamed away between the glob and now
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
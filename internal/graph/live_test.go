@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// writeCuries writes a graph of the Curies to dir, people lists who's in it
+func writeCuries(t *testing.T, dir string, people ...string) {
+	t.Helper()
+	file := &models.GraphFile{
+		Version: models.GraphFileVersion,
+		Graph:   models.Graph{},
+		Labels:  map[string]string{},
+	}
+	for i, name := range people {
+		id := "Q" + string(rune('1'+i))
+		file.Labels[id] = name
+		file.Graph[id] = []string{}
+		if i > 0 {
+			file.Graph[id] = []string{"Q1"}
+		}
+	}
+	if err := WriteGraphFile(filepath.Join(dir, "graph.json"), file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resolves(s *Store, name string) bool {
+	_, ok := s.Datasets()[0].Resolve(name)
+	return ok
+}
+
+func TestLiveReload(t *testing.T) {
+	dir := t.TempDir()
+	writeCuries(t, dir, "Marie Curie", "Pierre Curie")
+	live, err := NewLive(dir, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := live.Store()
+
+	// A graph that fails strict validation leaves the current store in place
+	broken := &models.GraphFile{Version: models.GraphFileVersion, Graph: models.Graph{"Q1": {"Q9"}}}
+	if err := WriteGraphFile(filepath.Join(dir, "graph.json"), broken); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.Reload(); err == nil {
+		t.Fatal("reloaded a graph with a dangling edge")
+	}
+	if live.Store() != old {
+		t.Error("a failed reload replaced the store")
+	}
+
+	writeCuries(t, dir, "Marie Curie", "Pierre Curie", "Irène Joliot-Curie")
+	if err := live.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !resolves(live.Store(), "Irène Joliot-Curie") {
+		t.Error("the new store doesn't know the new node")
+	}
+	// A search that picked the old store before the swap keeps seeing the old graph
+	if resolves(old, "Irène Joliot-Curie") || !resolves(old, "Pierre Curie") {
+		t.Error("the reload changed the old store")
+	}
+}
+
+func TestLiveWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeCuries(t, dir, "Marie Curie")
+	live, err := NewLive(dir, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go live.Watch(ctx, 5*time.Millisecond)
+
+	writeCuries(t, dir, "Marie Curie", "Pierre Curie")
+	deadline := time.Now().Add(2 * time.Second)
+	for !resolves(live.Store(), "Pierre Curie") {
+		if time.Now().After(deadline) {
+			t.Fatal("the watcher never picked up the new graph")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
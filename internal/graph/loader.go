@@ -2,27 +2,146 @@ package graph
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/Rani-Codes/sixth_degree/models"
 )
 
-// Load graph.json into memory
-func LoadGraph(filename string) (*models.Graph, error) {
-	file, err := os.Open(filename)
+// LoadGraph loads a graph file in any format and validates it, see Validate for what each mode does
+func LoadGraph(filename string, mode ValidationMode) (*models.Graph, error) {
+	file, err := LoadGraphFile(filename)
 	if err != nil {
-		// Stops program because if no graph then no BFS and no app
-		log.Fatal("Failed to open file to load graph. ", err)
+		return nil, err
+	}
+	if err := checkGraph(filename, file.Graph, mode); err != nil {
+		return nil, err
+	}
+	return &file.Graph, nil
+}
+
+// checkGraph validates a loaded graph and logs what lenient mode repaired
+func checkGraph(filename string, g models.Graph, mode ValidationMode) error {
+	report, err := Validate(g, mode)
+	if err != nil {
+		return fmt.Errorf("graph file %s: %w", filename, err)
+	}
+	if len(report.Issues) > 0 {
+		log.Printf("Repaired %s: %s", filename, report.Summary())
+	}
+	return nil
+}
+
+// LoadGraphFile reads any graph format: binary, compact, the versioned envelope or the original bare adjacency map
+// The format is told apart by the file's first bytes (binary) or its .jsonl extension (compact), not its name.
+// Title keyed files (version 0 and 1) are migrated to node IDs on the way in, using the QIDs from the
+// nodes file next to them when there is one. Version 0 files come back with empty metadata.
+func LoadGraphFile(filename string) (*models.GraphFile, error) {
+	file, _, err := LoadAnyGraph(filename)
+	return file, err
+}
+
+// LoadAnyGraph is LoadGraphFile plus the people of a full article graph, nil for graphs where everyone is a person
+func LoadAnyGraph(filename string) (*models.GraphFile, map[string]bool, error) {
+	binary, err := IsBinaryGraph(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	var file *models.GraphFile
+	var people map[string]bool
+	switch {
+	case binary:
+		file, people, err = LoadBinaryGraph(filename)
+	case isCompactName(filename):
+		file, people, err = LoadCompactGraph(filename)
+	default:
+		file, err = readGraphFile(filename)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Compact files stay keyed by title, see compactVersion
+	if file.Version < 2 && !isCompactName(filename) {
+		nodes, err := nodesNextTo(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("Migrating %s from version %d (keyed by title) to node IDs", filename, file.Version)
+		AssignIDs(file, nodes)
+	}
+	return file, people, nil
+}
+
+// nodesNextTo loads the nodes file that goes with a graph file, nil if there isn't one
+func nodesNextTo(filename string) (map[string]models.Person, error) {
+	lang, _, ok := parseFileName(filepath.Base(filename))
+	if !ok {
+		return nil, nil
+	}
+	nodes, err := LoadNodes(filepath.Join(filepath.Dir(filename), NodesFileName(lang)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return nodes, err
+}
+
+// readGraphFile decodes a graph file as it is on disk
+func readGraphFile(filename string) (*models.GraphFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open graph file: %w", err)
+	}
+	return decodeGraphFile(filename, data)
+}
+
+// decodeGraphFile decodes the JSON of a graph file, envelope or bare adjacency map, filename only goes in errors
+func decodeGraphFile(filename string, data []byte) (*models.GraphFile, error) {
+	// Peek at the top level keys to tell the formats apart
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("failed to decode graph file %s: %w", filename, err)
 	}
-	defer file.Close()
 
-	var graph models.Graph
-	if err := json.NewDecoder(file).Decode(&graph); err != nil {
-		// No graph then no BFS... have to log.Fatal
-		log.Fatal("Failed to decode graph file into graph type, (breaks BFS). ", err)
+	if isEnvelope(top) {
+		var file models.GraphFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to decode graph file %s: %w", filename, err)
+		}
+		if file.Version > models.GraphFileVersion {
+			return nil, fmt.Errorf("graph file %s is version %d, this build reads up to %d", filename, file.Version, models.GraphFileVersion)
+		}
+		if file.Graph == nil {
+			file.Graph = models.Graph{}
+		}
+		return &file, nil
 	}
 
-	return &graph, nil
+	// Old format, every key is a person and every value a neighbor list
+	graph := make(models.Graph, len(top))
+	for name, raw := range top {
+		var neighbors []string
+		if err := json.Unmarshal(raw, &neighbors); err != nil {
+			return nil, fmt.Errorf("failed to decode neighbors of %q in %s: %w", name, filename, err)
+		}
+		graph[name] = neighbors
+	}
+	return &models.GraphFile{Graph: graph}, nil
+}
 
+// isEnvelope reports whether the top level object is a GraphFile rather than an adjacency map
+// A person called "version" would have a list there, never a number
+func isEnvelope(top map[string]json.RawMessage) bool {
+	rawVersion, ok := top["version"]
+	if !ok {
+		return false
+	}
+	var version int
+	if err := json.Unmarshal(rawVersion, &version); err != nil {
+		return false
+	}
+	_, hasGraph := top["graph"]
+	return hasGraph
 }
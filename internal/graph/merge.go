@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// MergeReport says what the merge of a sharded fetch found
+type MergeReport struct {
+	Shards  int      `json:"shards"`
+	Nodes   int      `json:"nodes"`
+	Overlap []string `json:"overlap"` // Names more than one partial had, their links got combined
+	Missing []string `json:"missing"` // Names a shard was given but didn't fetch (failed pages)
+}
+
+// MergePartials combines the partial graphs of a sharded fetch into one graph keyed by name
+// The partials have to come from the same seed list and wiki and cover every shard exactly once,
+// anything else would quietly produce a graph with a hole in it
+func MergePartials(partials []*models.GraphFile) (*models.GraphFile, *MergeReport, error) {
+	if len(partials) == 0 {
+		return nil, nil, fmt.Errorf("no partial graphs to merge")
+	}
+
+	first := partials[0].Meta
+	if first.Shard == nil {
+		return nil, nil, fmt.Errorf("not a partial graph, it has no shard info")
+	}
+	count := first.Shard.Count
+	seen := make([]bool, count)
+	for _, p := range partials {
+		s := p.Meta.Shard
+		switch {
+		case s == nil:
+			return nil, nil, fmt.Errorf("not a partial graph, it has no shard info")
+		case s.Count != count:
+			return nil, nil, fmt.Errorf("shard %d is one of %d but shard %d is one of %d", s.Index, s.Count, first.Shard.Index, count)
+		case p.Meta.SeedHash != first.SeedHash:
+			return nil, nil, fmt.Errorf("shard %d was fetched from a different seed list than shard %d", s.Index, first.Shard.Index)
+		case p.Meta.Source != first.Source:
+			return nil, nil, fmt.Errorf("shard %d was fetched from %s, shard %d from %s", s.Index, p.Meta.Source, first.Shard.Index, first.Source)
+		case s.Index < 0 || s.Index >= count:
+			return nil, nil, fmt.Errorf("shard index %d out of range for %d shards", s.Index, count)
+		case seen[s.Index]:
+			return nil, nil, fmt.Errorf("shard %d shows up twice", s.Index)
+		}
+		seen[s.Index] = true
+	}
+	var absent []int
+	for i, ok := range seen {
+		if !ok {
+			absent = append(absent, i)
+		}
+	}
+	if len(absent) > 0 {
+		return nil, nil, fmt.Errorf("missing shards %v of %d", absent, count)
+	}
+
+	merged := &models.GraphFile{
+		Meta: models.GraphMeta{
+			SeedFile: first.SeedFile,
+			SeedHash: first.SeedHash,
+			Source:   first.Source,
+		},
+		Graph:     make(models.Graph),
+		Revisions: make(map[string]int64),
+		Tags:      make(models.Tags),
+	}
+	report := &MergeReport{Shards: count, Overlap: []string{}, Missing: []string{}}
+
+	for _, p := range partials {
+		// Partials are keyed by title (shards don't fetch metadata), TitleKeyed is a no-op copy then
+		if len(p.Labels) > 0 {
+			p = TitleKeyed(p)
+		}
+		if p.Meta.FetchedAt.After(merged.Meta.FetchedAt) {
+			merged.Meta.FetchedAt = p.Meta.FetchedAt
+		}
+		merged.Meta.Reused += p.Meta.Reused
+
+		for name, neighbors := range p.Graph {
+			if existing, dup := merged.Graph[name]; dup {
+				report.Overlap = append(report.Overlap, name)
+				neighbors = slices.Concat(existing, neighbors) // A new list, appending could write into a partial's
+			}
+			merged.Graph[name] = neighbors
+		}
+		for name, rev := range p.Revisions {
+			merged.Revisions[name] = max(merged.Revisions[name], rev)
+		}
+		for name, tags := range p.Tags {
+			merged.Tags[name] = tags
+		}
+	}
+
+	for _, p := range partials {
+		for _, name := range p.Meta.Shard.Assigned {
+			if _, ok := merged.Graph[name]; !ok {
+				report.Missing = append(report.Missing, name)
+			}
+		}
+	}
+	sort.Strings(report.Overlap)
+	sort.Strings(report.Missing)
+
+	Normalize(merged.Graph) // Dedupes the combined neighbor lists of overlapping names
+	merged.Meta.Failed = len(report.Missing)
+	report.Nodes = len(merged.Graph)
+	return merged, report, nil
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// partial is one shard's graph, keyed by title like a sharded fetch writes it
+func partial(index, count int, assigned []string, graph models.Graph) *models.GraphFile {
+	return &models.GraphFile{
+		Version: 1,
+		Meta: models.GraphMeta{
+			FetchedAt: time.Date(2025, 3, 1, index, 0, 0, 0, time.UTC),
+			SeedHash:  "seeds-v1",
+			Source:    "en.wikipedia.org",
+			Shard:     &models.Shard{Index: index, Count: count, Assigned: assigned},
+		},
+		Graph:     graph,
+		Revisions: map[string]int64{},
+	}
+}
+
+func TestMergePartials(t *testing.T) {
+	tests := []struct {
+		name     string
+		partials func() []*models.GraphFile
+		graph    models.Graph
+		overlap  []string
+		missing  []string
+		err      string
+	}{
+		{
+			name: "disjoint shards",
+			partials: func() []*models.GraphFile {
+				return []*models.GraphFile{
+					partial(1, 2, []string{"B"}, models.Graph{"B": {"A"}}),
+					partial(0, 2, []string{"A", "C"}, models.Graph{"A": {"B", "C"}, "C": {}}),
+				}
+			},
+			graph:   models.Graph{"A": {"B", "C"}, "B": {"A"}, "C": {}},
+			overlap: []string{},
+			missing: []string{},
+		},
+		{
+			name: "a page in two shards gets both link lists",
+			partials: func() []*models.GraphFile {
+				return []*models.GraphFile{
+					partial(0, 2, []string{"A", "B"}, models.Graph{"A": {"C", "B"}, "B": {}}),
+					partial(1, 2, []string{"A", "C"}, models.Graph{"A": {"B", "D"}, "C": {"A"}}),
+				}
+			},
+			graph:   models.Graph{"A": {"B", "C", "D"}, "B": {}, "C": {"A"}},
+			overlap: []string{"A"},
+			missing: []string{},
+		},
+		{
+			name: "pages a shard failed to fetch are reported",
+			partials: func() []*models.GraphFile {
+				return []*models.GraphFile{
+					partial(0, 2, []string{"A", "B"}, models.Graph{"A": {"C"}}),
+					partial(1, 2, []string{"C"}, models.Graph{"C": {"A"}}),
+				}
+			},
+			graph:   models.Graph{"A": {"C"}, "C": {"A"}},
+			overlap: []string{},
+			missing: []string{"B"},
+		},
+		{
+			name: "missing shard",
+			partials: func() []*models.GraphFile {
+				return []*models.GraphFile{partial(0, 3, nil, models.Graph{}), partial(2, 3, nil, models.Graph{})}
+			},
+			err: "missing shards [1] of 3",
+		},
+		{
+			name: "shard twice",
+			partials: func() []*models.GraphFile {
+				return []*models.GraphFile{partial(0, 2, nil, models.Graph{}), partial(0, 2, nil, models.Graph{})}
+			},
+			err: "shard 0 shows up twice",
+		},
+		{
+			name: "different seed lists",
+			partials: func() []*models.GraphFile {
+				other := partial(1, 2, nil, models.Graph{})
+				other.Meta.SeedHash = "seeds-v2"
+				return []*models.GraphFile{partial(0, 2, nil, models.Graph{}), other}
+			},
+			err: "different seed list",
+		},
+		{
+			name: "not a partial",
+			partials: func() []*models.GraphFile {
+				return []*models.GraphFile{{Graph: models.Graph{}}}
+			},
+			err: "no shard info",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partials := tt.partials()
+			merged, report, err := MergePartials(partials)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(merged.Graph, tt.graph) {
+				t.Errorf("graph = %v, want %v", merged.Graph, tt.graph)
+			}
+			if !reflect.DeepEqual(report.Overlap, tt.overlap) || !reflect.DeepEqual(report.Missing, tt.missing) {
+				t.Errorf("overlap %v, missing %v, want %v and %v", report.Overlap, report.Missing, tt.overlap, tt.missing)
+			}
+			if report.Nodes != len(tt.graph) || merged.Meta.Failed != len(tt.missing) {
+				t.Errorf("report says %d nodes and %d failed", report.Nodes, merged.Meta.Failed)
+			}
+			// The merge builds its own lists, the partials are left as they were
+			if fresh := tt.partials(); !reflect.DeepEqual(partials, fresh) {
+				t.Errorf("merge changed the partials: %v", partials)
+			}
+		})
+	}
+}
+
+// Overlapping link lists are combined into a new list, never into the spare capacity of a partial's
+func TestMergePartialsDoesNotShareLists(t *testing.T) {
+	links := make([]string, 1, 4)
+	links[0] = "B"
+	spare := links[:2]
+	spare[1] = "untouched"
+
+	a := partial(0, 2, []string{"A"}, models.Graph{"A": links})
+	b := partial(1, 2, []string{"A"}, models.Graph{"A": {"C"}})
+	if _, _, err := MergePartials([]*models.GraphFile{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	if spare[1] != "untouched" {
+		t.Errorf("the merge wrote %q past the end of a partial's list", spare[1])
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package graph
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps a whole file read-only, the returned func unmaps it
+// The pages live in the page cache instead of the Go heap, the kernel can drop and reread them as needed
+func mapFile(f *os.File, size int) ([]byte, func() error, error) {
+	if size == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
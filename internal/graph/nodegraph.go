@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"encoding/json"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// adjacency is where a NodeGraph's nodes, labels and neighbor lists come from
+type adjacency interface {
+	Len() int                     // Nodes with a neighbor list
+	NumEdges() int                // Edges of every neighbor list together
+	Has(id string) bool           // Whether id has a neighbor list, a link target alone doesn't count
+	Neighbors(id string) []string // nil for a node without a list, don't modify the result
+	Label(id string) string       // Title to show, "" when it's the ID
+	IDs() []string                // Every node with a neighbor list, sorted
+}
+
+// NodeGraph is the graph the server searches: the lean adjacency of the graph file plus node attributes and the
+// attributes of the edges that have any, kept on the side. A plain link graph costs nothing over its adjacency,
+// models.Node and models.Edge are only built when something asks for one.
+type NodeGraph struct {
+	adj       adjacency
+	attrs     map[string]map[string]string           // ID -> node attributes
+	edgeAttrs map[string]map[string]models.EdgeAttrs // From -> to -> attributes, only for edges that have any
+}
+
+// mapAdjacency is the adjacency of a decoded graph file, it shares the file's maps
+type mapAdjacency struct {
+	graph  models.Graph
+	labels map[string]string
+	edges  int
+}
+
+func (a *mapAdjacency) Len() int      { return len(a.graph) }
+func (a *mapAdjacency) NumEdges() int { return a.edges }
+
+func (a *mapAdjacency) Has(id string) bool {
+	_, ok := a.graph[id]
+	return ok
+}
+
+func (a *mapAdjacency) Neighbors(id string) []string { return a.graph[id] }
+func (a *mapAdjacency) Label(id string) string       { return a.labels[id] }
+func (a *mapAdjacency) IDs() []string                { return sortedKeys(a.graph) }
+
+// NewNodeGraph wraps a graph file for searching, it shares the file's adjacency, labels and attributes
+// instead of copying them, so the file shouldn't change afterwards
+func NewNodeGraph(file *models.GraphFile) *NodeGraph {
+	return &NodeGraph{
+		adj:       &mapAdjacency{graph: file.Graph, labels: file.Labels, edges: CountEdges(file.Graph)},
+		attrs:     file.Attrs,
+		edgeAttrs: file.EdgeAttrs,
+	}
+}
+
+// Len returns the number of nodes
+func (g *NodeGraph) Len() int { return g.adj.Len() }
+
+// NumEdges returns the number of edges
+func (g *NodeGraph) NumEdges() int { return g.adj.NumEdges() }
+
+// Has reports whether id is a node of the graph
+func (g *NodeGraph) Has(id string) bool { return g.adj.Has(id) }
+
+// Neighbors returns the IDs a node links to, the result is shared and mustn't be modified
+func (g *NodeGraph) Neighbors(id string) []string { return g.adj.Neighbors(id) }
+
+// Label returns the title stored for a node, "" when its ID is the title
+func (g *NodeGraph) Label(id string) string { return g.adj.Label(id) }
+
+// IDs returns every node ID in sorted order
+func (g *NodeGraph) IDs() []string { return g.adj.IDs() }
+
+// Attrs returns a node's attributes, nil when it has none
+func (g *NodeGraph) Attrs(id string) map[string]string { return g.attrs[id] }
+
+// EdgeAttrs returns the attributes of the edge from -> to, zero when it has none
+func (g *NodeGraph) EdgeAttrs(from, to string) models.EdgeAttrs { return g.edgeAttrs[from][to] }
+
+// HasEdgeAttrs reports whether any edge has a weight, type or provenance
+func (g *NodeGraph) HasEdgeAttrs() bool { return len(g.edgeAttrs) > 0 }
+
+// Node builds a node with its label, attributes and edges
+func (g *NodeGraph) Node(id string) (*models.Node, bool) {
+	if !g.Has(id) {
+		return nil, false
+	}
+	neighbors := g.Neighbors(id)
+	node := &models.Node{ID: id, Label: g.Label(id), Attrs: g.attrs[id], Edges: make([]models.Edge, len(neighbors))}
+	attrs := g.edgeAttrs[id]
+	for i, to := range neighbors {
+		node.Edges[i] = models.Edge{To: to, EdgeAttrs: attrs[to]}
+	}
+	return node, true
+}
+
+// MarshalJSON writes the nodes keyed by ID with their labels, attributes and edges, what /api/graph?nodes=1 sends
+func (g *NodeGraph) MarshalJSON() ([]byte, error) {
+	nodes := make(map[string]*models.Node, g.Len())
+	for _, id := range g.IDs() {
+		nodes[id], _ = g.Node(id)
+	}
+	return json.Marshal(nodes)
+}
+
+// attrNames lists the names of every node attribute in the graph, sorted
+func (g *NodeGraph) attrNames() []string {
+	names := make(map[string]bool)
+	for _, attrs := range g.attrs {
+		for name := range attrs {
+			names[name] = true
+		}
+	}
+	return sortedKeys(names)
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// attrFile is a version 3 graph with everything the envelope holds: labels, aliases, tags, revisions, node and
+// edge attributes, and a node that doesn't link anywhere
+func attrFile() *models.GraphFile {
+	return &models.GraphFile{
+		Version: models.GraphFileVersion,
+		Meta: models.GraphMeta{
+			FetchedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+			Source:    "en.wikipedia.org",
+			Nodes:     4,
+			Edges:     4,
+		},
+		Graph: models.Graph{
+			"Q1": {"Q2", "Q3"},
+			"Q2": {"Q1"},
+			"Q3": {"Q4"},
+			"Q4": {},
+		},
+		Revisions: map[string]int64{"Q1": 101, "Q2": 102},
+		Tags:      models.Tags{"Q1": {"physics", "chemistry"}, "Q2": {"physics"}},
+		Labels:    map[string]string{"Q1": "Marie Curie", "Q2": "Pierre Curie", "Q3": "Irene Joliot-Curie", "Q4": "Frederic Joliot-Curie"},
+		Aliases:   map[string]string{"Maria Sklodowska": "Q1"},
+		Attrs:     map[string]map[string]string{"Q1": {"born": "1867", "field": "physics"}, "Q3": {"born": "1897"}},
+		EdgeAttrs: map[string]map[string]models.EdgeAttrs{
+			"Q1": {"Q2": {Weight: 2, Type: models.RelationSpouse, Provenance: ProvenanceWikidata}},
+			"Q3": {"Q4": {Type: models.RelationSpouse}},
+		},
+	}
+}
+
+func TestGraphFileAttrsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	if err := WriteGraphFile(path, attrFile()); err != nil {
+		t.Fatal(err)
+	}
+	file, err := LoadGraphFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(file, attrFile()) {
+		t.Errorf("loaded %+v\nwant %+v", file, attrFile())
+	}
+}
+
+// A version 2 file has no attributes, it loads as is with none
+func TestGraphFileVersion2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	v2 := `{"version":2,"meta":{"source":"en.wikipedia.org"},"graph":{"Q1":["Q2"],"Q2":[]},"labels":{"Q1":"Marie Curie"}}`
+	if err := os.WriteFile(path, []byte(v2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := LoadDataset(path, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.Version != 2 || ds.Graph.HasEdgeAttrs() || ds.Graph.Attrs("Q1") != nil {
+		t.Errorf("version %d dataset has attributes", ds.Version)
+	}
+	node, ok := ds.Graph.Node("Q1")
+	if want := (&models.Node{ID: "Q1", Label: "Marie Curie", Edges: []models.Edge{{To: "Q2"}}}); !ok || !reflect.DeepEqual(node, want) {
+		t.Errorf("node = %+v, want %+v", node, want)
+	}
+}
+
+func TestNodeGraph(t *testing.T) {
+	g := NewNodeGraph(attrFile())
+
+	node, ok := g.Node("Q1")
+	want := &models.Node{
+		ID:    "Q1",
+		Label: "Marie Curie",
+		Attrs: map[string]string{"born": "1867", "field": "physics"},
+		Edges: []models.Edge{
+			{To: "Q2", EdgeAttrs: models.EdgeAttrs{Weight: 2, Type: models.RelationSpouse, Provenance: ProvenanceWikidata}},
+			{To: "Q3"},
+		},
+	}
+	if !ok || !reflect.DeepEqual(node, want) {
+		t.Errorf("node = %+v, want %+v", node, want)
+	}
+	if _, ok := g.Node("Q9"); ok {
+		t.Error("found a node that isn't in the graph")
+	}
+	if got := g.attrNames(); !reflect.DeepEqual(got, []string{"born", "field"}) {
+		t.Errorf("attribute names = %v", got)
+	}
+
+	// What /api/graph?nodes=1 sends
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes map[string]models.Node
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 4 || !reflect.DeepEqual(nodes["Q1"], *want) {
+		t.Errorf("marshalled %s", data)
+	}
+	if q4 := nodes["Q4"]; q4.Label != "Frederic Joliot-Curie" || len(q4.Edges) != 0 {
+		t.Errorf("Q4 = %+v", q4)
+	}
+}
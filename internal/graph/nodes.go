@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// NodesFileName returns the per person metadata file that sits next to a language's graph file
+func NodesFileName(lang string) string {
+	return sideFileName("nodes", lang)
+}
+
+// ContextsFileName returns the link context file that sits next to a language's graph file
+func ContextsFileName(lang string) string {
+	return sideFileName("contexts", lang)
+}
+
+// RelationsFileName returns the typed Wikidata edges file that sits next to a language's graph file
+func RelationsFileName(lang string) string {
+	return sideFileName("relations", lang)
+}
+
+// sideFileName names the optional files that go with a graph: <kind>.json for English, <kind>.<lang>.json otherwise
+func sideFileName(kind, lang string) string {
+	if lang == "" || lang == DefaultLang {
+		return kind + ".json"
+	}
+	return kind + "." + lang + ".json"
+}
+
+// LoadNodes reads a nodes file (seed name -> metadata)
+func LoadNodes(filename string) (map[string]models.Person, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSideFile[map[string]models.Person]("nodes", filename, data)
+}
+
+// LoadContexts reads a link context file (from -> to -> where in from's article the link sits)
+func LoadContexts(filename string) (map[string]map[string]models.LinkContext, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSideFile[map[string]map[string]models.LinkContext]("contexts", filename, data)
+}
+
+// decodeSideFile decodes the JSON of a nodes, contexts or relations file, kind and filename go in the error
+func decodeSideFile[T any](kind, filename string, data []byte) (T, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("failed to decode %s file %s: %w", kind, filename, err)
+	}
+	return v, nil
+}
+
+// WriteContexts writes a link context file atomically
+func WriteContexts(filename string, contexts map[string]map[string]models.LinkContext) error {
+	data, err := json.MarshalIndent(contexts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal link contexts: %w", err)
+	}
+	return WriteFileAtomic(filename, data)
+}
+
+// LoadRelations reads a typed edges file (from -> typed edges)
+func LoadRelations(filename string) (models.TypedGraph, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSideFile[models.TypedGraph]("relations", filename, data)
+}
+
+// WriteRelations writes a typed edges file atomically
+func WriteRelations(filename string, typed models.TypedGraph) error {
+	data, err := json.MarshalIndent(typed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal relations: %w", err)
+	}
+	return WriteFileAtomic(filename, data)
+}
+
+// WriteNodes writes a nodes file atomically, keys come out sorted so the file is deterministic
+func WriteNodes(filename string, nodes map[string]models.Person) error {
+	data, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal nodes: %w", err)
+	}
+	return WriteFileAtomic(filename, data)
+}
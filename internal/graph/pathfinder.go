@@ -1,3 +1,117 @@
 package graph
 
 // Path finding logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// ProvenanceWikidata marks edges read from Wikidata claims rather than article links
+const ProvenanceWikidata = "wikidata"
+
+// Shorthands clients can send instead of listing every relation
+var relationGroups = map[string][]string{
+	"family":    {models.RelationSpouse, models.RelationChild, models.RelationParent, models.RelationSibling},
+	"colleague": {models.RelationEmployer, models.RelationAward, models.RelationMemberOf, models.RelationTeam},
+}
+
+var knownRelations = map[string]bool{
+	models.RelationSpouse: true, models.RelationChild: true, models.RelationParent: true, models.RelationSibling: true,
+	models.RelationEmployer: true, models.RelationAward: true, models.RelationMemberOf: true, models.RelationTeam: true,
+}
+
+// ExpandRelations turns a request's relation list ("family", "employer", ...) into the set of relation types to follow
+func ExpandRelations(relations []string) (map[string]bool, error) {
+	allowed := make(map[string]bool)
+	for _, r := range relations {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if group, ok := relationGroups[r]; ok {
+			for _, g := range group {
+				allowed[g] = true
+			}
+			continue
+		}
+		if !knownRelations[r] {
+			return nil, fmt.Errorf("unknown relation %q", r)
+		}
+		allowed[r] = true
+	}
+	return allowed, nil
+}
+
+// RelationGraph builds a node graph out of the typed edges whose relation is allowed, each edge typed with its relation
+// Every node of the hyperlink graph is kept so a missing person still reads "no path" instead of "not found"
+func RelationGraph(nodes *NodeGraph, typed models.TypedGraph, allowed map[string]bool) *NodeGraph {
+	file := &models.GraphFile{Graph: make(models.Graph, nodes.Len()), EdgeAttrs: make(map[string]map[string]models.EdgeAttrs)}
+	for _, id := range nodes.IDs() {
+		file.Graph[id] = nil
+	}
+	for from, edges := range typed {
+		if !nodes.Has(from) {
+			continue
+		}
+		for _, e := range edges {
+			if !allowed[e.Relation] {
+				continue
+			}
+			// Two relations between the same people are one edge, typed with the first
+			if _, dup := file.EdgeAttrs[from][e.To]; dup {
+				continue
+			}
+			if file.EdgeAttrs[from] == nil {
+				file.EdgeAttrs[from] = make(map[string]models.EdgeAttrs)
+			}
+			file.EdgeAttrs[from][e.To] = models.EdgeAttrs{Type: e.Relation, Provenance: ProvenanceWikidata}
+			file.Graph[from] = append(file.Graph[from], e.To)
+		}
+	}
+	// Sorted edges keep the BFS (and so the path picked between equally short ones) deterministic
+	for _, neighbors := range file.Graph {
+		sort.Strings(neighbors)
+	}
+	return NewNodeGraph(file)
+}
+
+// RelationHops labels each edge of a path found on a RelationGraph with the relation behind it
+// label turns the path's node IDs into the titles shown in the hops
+func RelationHops(typed models.TypedGraph, allowed map[string]bool, path []string, label func(string) string) []models.Hop {
+	if len(path) < 2 {
+		return nil
+	}
+	hops := make([]models.Hop, 0, len(path)-1)
+	for i := 0; i+1 < len(path); i++ {
+		hop := models.Hop{From: label(path[i]), To: label(path[i+1])}
+		for _, e := range typed[path[i]] {
+			if e.To == path[i+1] && allowed[e.Relation] {
+				hop.Relation = e.Relation
+				hop.Summary = relationSummary(hop.From, hop.To, e)
+				break
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// relationSummary renders a typed edge, e.g. "Marie Curie's spouse is Pierre Curie"
+func relationSummary(from, to string, e models.TypedEdge) string {
+	via := e.ViaLabel
+	if via == "" {
+		via = e.Via
+	}
+	switch e.Relation {
+	case models.RelationEmployer:
+		return fmt.Sprintf("%s and %s share an employer (%s)", from, to, via)
+	case models.RelationAward:
+		return fmt.Sprintf("%s and %s received the same award (%s)", from, to, via)
+	case models.RelationMemberOf:
+		return fmt.Sprintf("%s and %s are members of the same group (%s)", from, to, via)
+	case models.RelationTeam:
+		return fmt.Sprintf("%s and %s played for the same team (%s)", from, to, via)
+	}
+	return fmt.Sprintf("%s's %s is %s", from, e.Relation, to)
+}
@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+func TestExpandRelations(t *testing.T) {
+	tests := []struct {
+		relations []string
+		want      []string
+		err       string
+	}{
+		{relations: []string{"family"}, want: []string{"child", "parent", "sibling", "spouse"}},
+		{relations: []string{" Employer ", "award"}, want: []string{"award", "employer"}},
+		{relations: []string{"colleague", "spouse"}, want: []string{"award", "employer", "member_of", "spouse", "team"}},
+		{relations: []string{"family", "rival"}, err: `unknown relation "rival"`},
+		{relations: nil, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.relations, ","), func(t *testing.T) {
+			allowed, err := ExpandRelations(tt.relations)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortedKeys(allowed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// curieGraph is a hyperlink graph of four people, the typed edges below connect them differently
+func curieGraph() *NodeGraph {
+	return NewNodeGraph(&models.GraphFile{
+		Graph: models.Graph{
+			"Q1": {"Q5"},
+			"Q2": {},
+			"Q3": {"Q1"},
+			"Q4": {},
+			"Q5": {"Q4"},
+		},
+		Labels: map[string]string{"Q1": "Marie Curie", "Q2": "Pierre Curie", "Q3": "Irene Joliot-Curie", "Q4": "Eve Curie", "Q5": "Paris"},
+	})
+}
+
+var curieRelations = models.TypedGraph{
+	"Q1": {
+		{To: "Q2", Relation: models.RelationAward, Via: "Q100", ViaLabel: "Nobel Prize in Physics"},
+		{To: "Q2", Relation: models.RelationSpouse},
+		{To: "Q3", Relation: models.RelationChild},
+	},
+	"Q2": {{To: "Q1", Relation: models.RelationAward, Via: "Q100", ViaLabel: "Nobel Prize in Physics"}, {To: "Q1", Relation: models.RelationSpouse}},
+	"Q3": {{To: "Q1", Relation: models.RelationParent}, {To: "Q4", Relation: models.RelationSibling}},
+	"Q4": {{To: "Q3", Relation: models.RelationSibling}},
+	"Q9": {{To: "Q1", Relation: models.RelationSpouse}}, // Not in the hyperlink graph
+}
+
+func TestRelationGraph(t *testing.T) {
+	allowed, err := ExpandRelations([]string{"family"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := RelationGraph(curieGraph(), curieRelations, allowed)
+
+	want := map[string][]string{"Q1": {"Q2", "Q3"}, "Q2": {"Q1"}, "Q3": {"Q1", "Q4"}, "Q4": {"Q3"}, "Q5": nil}
+	if g.Len() != len(want) || g.Has("Q9") {
+		t.Errorf("relation graph has %v, want %v", g.IDs(), sortedKeys(want))
+	}
+	for id, neighbors := range want {
+		if got := g.Neighbors(id); !reflect.DeepEqual(got, neighbors) {
+			t.Errorf("neighbors of %s = %v, want %v", id, got, neighbors)
+		}
+	}
+	if got := g.EdgeAttrs("Q1", "Q3"); got != (models.EdgeAttrs{Type: models.RelationChild, Provenance: ProvenanceWikidata}) {
+		t.Errorf("Q1 -> Q3 attrs = %+v", got)
+	}
+	// Spouse and award connect Q1 and Q2, only the allowed one types the edge
+	if got := g.EdgeAttrs("Q1", "Q2").Type; got != models.RelationSpouse {
+		t.Errorf("Q1 -> Q2 type = %q, want spouse", got)
+	}
+}
+
+// A dataset builds each relation set's graph once, however the set is spelled
+func TestDatasetRelationGraph(t *testing.T) {
+	ds := &Dataset{Graph: curieGraph(), Relations: curieRelations}
+	family, err := ExpandRelations([]string{"family"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spelledOut, err := ExpandRelations([]string{"Spouse", "sibling", "parent", "child", "spouse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := ds.RelationGraph(family)
+	if again := ds.RelationGraph(spelledOut); again != g {
+		t.Error("the same relations built a second graph")
+	}
+	if got := g.Neighbors("Q3"); !reflect.DeepEqual(got, []string{"Q1", "Q4"}) {
+		t.Errorf("neighbors of Q3 = %v", got)
+	}
+	if award := ds.RelationGraph(map[string]bool{models.RelationAward: true}); award == g || award.Neighbors("Q3") != nil {
+		t.Errorf("award graph shares the family one or has Q3's family edges")
+	}
+}
+
+func TestRelationPath(t *testing.T) {
+	label := func(id string) string { return curieGraph().Label(id) }
+	tests := []struct {
+		relations []string
+		from, to  string
+		path      []string
+		summaries []string
+		err       string
+	}{
+		{
+			relations: []string{"family"}, from: "Q2", to: "Q4",
+			path: []string{"Q2", "Q1", "Q3", "Q4"},
+			summaries: []string{
+				"Pierre Curie's spouse is Marie Curie",
+				"Marie Curie's child is Irene Joliot-Curie",
+				"Irene Joliot-Curie's sibling is Eve Curie",
+			},
+		},
+		{
+			relations: []string{"award"}, from: "Q2", to: "Q1",
+			path:      []string{"Q2", "Q1"},
+			summaries: []string{"Pierre Curie and Marie Curie received the same award (Nobel Prize in Physics)"},
+		},
+		// Paris is in the graph without any typed edge, so there's no path rather than no node
+		{relations: []string{"family"}, from: "Q1", to: "Q5", err: "no path from Q1 to Q5"},
+		{relations: []string{"colleague"}, from: "Q1", to: "Q3", err: "no path from Q1 to Q3"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.relations, ",")+" "+tt.from+"-"+tt.to, func(t *testing.T) {
+			allowed, err := ExpandRelations(tt.relations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path, err := FindShortestPath(RelationGraph(curieGraph(), curieRelations, allowed), tt.from, tt.to, nil)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Fatalf("path = %v, want %v", path, tt.path)
+			}
+			var summaries []string
+			for _, hop := range RelationHops(curieRelations, allowed, path, label) {
+				summaries = append(summaries, hop.Summary)
+			}
+			if !reflect.DeepEqual(summaries, tt.summaries) {
+				t.Errorf("hops = %q, want %q", summaries, tt.summaries)
+			}
+		})
+	}
+}
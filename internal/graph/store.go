@@ -0,0 +1,736 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// DefaultLang is the language edition served when a request doesn't pick one
+const DefaultLang = "en"
+
+// VariantProse is the graph built from links written in article prose only, no infobox or navbox links
+const VariantProse = "prose"
+
+// VariantFull is the full article graph: links to any article up to a crawl depth, with people as the only endpoints
+// It's stored in the compact format (see WriteCompactGraph) since it runs into millions of edges
+const VariantFull = "full"
+
+// FileName returns the graph file for a language edition and variant ("" is the graph of people)
+// English keeps the original graph.json name, everything else is graph.<lang>[.<variant>].json
+// and the full article graph is graph.<lang>.full.jsonl
+func FileName(lang, variant string) string {
+	if lang == "" {
+		lang = DefaultLang
+	}
+	if variant == VariantFull {
+		return "graph." + lang + "." + variant + ".jsonl"
+	}
+	if variant != "" {
+		return "graph." + lang + "." + variant + ".json"
+	}
+	if lang == DefaultLang {
+		return "graph.json"
+	}
+	return "graph." + lang + ".json"
+}
+
+// BinaryFileName is where cmd/convert puts the binary copy of a graph: FileName with a .bin extension
+func BinaryFileName(lang, variant string) string {
+	name := FileName(lang, variant)
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".bin"
+}
+
+// parseFileName is the reverse of FileName and BinaryFileName, ok is false for files that aren't graphs
+func parseFileName(name string) (lang, variant string, ok bool) {
+	if name == "graph.json" || name == "graph.bin" {
+		return DefaultLang, "", true
+	}
+	if strings.HasSuffix(name, ".bin") {
+		return parseFileName(strings.TrimSuffix(name, ".bin") + ".json")
+	}
+	if strings.HasSuffix(name, ".jsonl") {
+		lang, variant, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(name, "graph."), ".jsonl"), ".")
+		return lang, variant, strings.HasPrefix(name, "graph.") && lang != "" && variant == VariantFull
+	}
+	if !strings.HasPrefix(name, "graph.") || !strings.HasSuffix(name, ".json") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(name, "graph."), ".json"), ".")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return parts[0], "", true
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+// DefaultSnapshot names the graphs in the server's own directory, what a request that doesn't pick a snapshot gets
+const DefaultSnapshot = "latest"
+
+// SnapshotsDir is the directory next to graph.json holding other snapshots, one subdirectory each
+// (snapshots/2025/graph.json, snapshots/2025/graph.en.prose.json, ...) named after the subdirectory
+const SnapshotsDir = "snapshots"
+
+// datasetName is how the store keys a dataset: "de" for the full German graph, "de.prose" for its prose variant
+func datasetName(lang, variant string) string {
+	if lang == "" {
+		lang = DefaultLang
+	}
+	if variant == "" {
+		return lang
+	}
+	return lang + "." + variant
+}
+
+// Dataset is one loaded graph plus everything we know about it
+// Everything is keyed by node ID (the QID, or the title for pages without one), Label turns an ID back into a title
+type Dataset struct {
+	Name      string // Language code plus variant, e.g. "en" or "en.prose"
+	Snapshot  string // Snapshot it belongs to, DefaultSnapshot or a subdirectory of SnapshotsDir
+	Path      string // File the graph was loaded from
+	Lang      string
+	Variant   string                                   // Empty for all links between people, VariantProse for prose links only
+	Graph     *NodeGraph                               // Adjacency with the labels, node attributes and edge attributes
+	Version   int                                      // Graph file version, older title keyed files are migrated on load
+	Meta      models.GraphMeta                         // Empty for files from before the envelope
+	Tags      models.Tags                              // Seed list tags, nil for graphs built from the plain text seed list
+	Nodes     map[string]models.Person                 // Per person metadata from the nodes file, nil if there isn't one
+	Contexts  map[string]map[string]models.LinkContext // from -> to -> where the link sits, nil if there isn't a contexts file
+	Relations models.TypedGraph                        // Typed Wikidata edges, nil if there isn't a relations file
+	People    map[string]bool                          // Full article graph only: the nodes that are people, nil means every node is one
+
+	aliases     map[string]string // Former titles, redirects and other names -> ID
+	lookup      map[string]string // Lower cased ID, label or alias -> ID, built on first use
+	shared      map[string]bool   // Labels more than one node has, built with the lookup
+	lookupOnce  sync.Once
+	personIndex []string // IDs of the people sorted by label, what /api/people goes through, built on first use
+	personOnce  sync.Once
+	labelGraph  models.Graph // Graph keyed by label, built on first use
+	labelOnce   sync.Once
+	communities map[string]int // ID -> community, found on first use
+	commOnce    sync.Once
+	relGraphs   map[string]*NodeGraph // Sorted allowed relations joined by commas -> RelationGraph, built on first use
+	relMu       sync.Mutex
+}
+
+// newDataset wraps a loaded graph, file has the rest of its envelope (the graph of a binary file isn't in it)
+// The side files are still keyed by seed name and get rekeyed by ID here
+func newDataset(name, lang, variant string, file *models.GraphFile, g *NodeGraph) *Dataset {
+	return &Dataset{Name: name, Lang: lang, Variant: variant, Graph: g, Version: file.Version, Meta: file.Meta, Tags: file.Tags, aliases: file.Aliases}
+}
+
+// buildLookup indexes every ID, label and alias by its lower case
+func (ds *Dataset) buildLookup() {
+	ids := ds.Graph.IDs()
+	ds.lookup = make(map[string]string, 2*len(ids)+len(ds.aliases))
+	ds.shared = make(map[string]bool)
+	// Aliases first so a real ID or label always wins over one
+	for alias, id := range ds.aliases {
+		ds.lookup[strings.ToLower(alias)] = id
+	}
+	// A label several nodes share resolves to the first of them, each one by its "label (id)" too
+	owners := make(map[string]string, len(ids))
+	for _, id := range ids {
+		label := ds.Graph.Label(id)
+		if label == "" {
+			continue
+		}
+		if _, taken := owners[label]; taken {
+			ds.shared[label] = true
+			continue
+		}
+		owners[label] = id
+	}
+	for _, id := range ids {
+		label := ds.Graph.Label(id)
+		if ds.shared[label] {
+			ds.lookup[strings.ToLower(disambiguate(label, id))] = id
+		}
+		if owners[label] == id {
+			ds.lookup[strings.ToLower(label)] = id
+		}
+	}
+	for _, id := range ids {
+		ds.lookup[strings.ToLower(id)] = id
+	}
+}
+
+// disambiguate tells apart nodes that share a label, e.g. "John Smith (Q1)"
+func disambiguate(label, id string) string {
+	return label + " (" + id + ")"
+}
+
+// Info describes the dataset for /api/graphs
+func (ds *Dataset) Info() models.GraphInfo {
+	return models.GraphInfo{
+		Graph:   ds.Snapshot,
+		Lang:    ds.Lang,
+		Variant: ds.Variant,
+		Default: ds.Snapshot == DefaultSnapshot && ds.Lang == DefaultLang && ds.Variant == "",
+		Nodes:   ds.Graph.Len(),
+		Edges:   ds.Graph.NumEdges(),
+		Version: ds.Version,
+		Meta:    ds.Meta,
+	}
+}
+
+// Resolve finds the node ID for a QID, a title or any alias the graph knows (case doesn't matter)
+func (ds *Dataset) Resolve(name string) (string, bool) {
+	if ds.Graph.Has(name) {
+		return name, true
+	}
+	ds.lookupOnce.Do(ds.buildLookup)
+	id, ok := ds.lookup[strings.ToLower(strings.TrimSpace(name))]
+	return id, ok
+}
+
+// IsPerson reports whether a node can start or end a search, in the full article graph the rest are just stepping stones
+func (ds *Dataset) IsPerson(id string) bool {
+	return ds.People == nil || ds.People[id]
+}
+
+// Label returns the title to show for a node ID, with the ID added when another node has the same title
+func (ds *Dataset) Label(id string) string {
+	label := ds.Graph.Label(id)
+	if label == "" {
+		return id
+	}
+	ds.lookupOnce.Do(ds.buildLookup)
+	if ds.shared[label] {
+		return disambiguate(label, id)
+	}
+	return label
+}
+
+// LabelPath turns a path of IDs into the titles to show
+func (ds *Dataset) LabelPath(path []string) []string {
+	labels := make([]string, len(path))
+	for i, id := range path {
+		labels[i] = ds.Label(id)
+	}
+	return labels
+}
+
+// PersonIndex returns the IDs of everyone a search can start or end at, sorted by the title we show
+func (ds *Dataset) PersonIndex() []string {
+	ds.personOnce.Do(ds.indexPeople)
+	return ds.personIndex
+}
+
+// indexPeople sorts the people by label, the full article graph also holds universities, cities, ... which aren't people to pick
+func (ds *Dataset) indexPeople() {
+	ds.personIndex = make([]string, 0, ds.Graph.Len())
+	for _, id := range ds.Graph.IDs() {
+		if ds.IsPerson(id) {
+			ds.personIndex = append(ds.personIndex, id)
+		}
+	}
+	sort.Slice(ds.personIndex, func(i, j int) bool { return ds.Label(ds.personIndex[i]) < ds.Label(ds.personIndex[j]) })
+}
+
+// LabelGraph returns the adjacency map keyed by title, what the frontend draws
+func (ds *Dataset) LabelGraph() models.Graph {
+	ds.labelOnce.Do(func() {
+		ds.labelGraph = make(models.Graph, ds.Graph.Len())
+		for _, id := range ds.Graph.IDs() {
+			ids := ds.Graph.Neighbors(id)
+			neighbors := make([]string, len(ids))
+			for i, to := range ids {
+				neighbors[i] = ds.Label(to)
+			}
+			ds.labelGraph[ds.Label(id)] = neighbors
+		}
+	})
+	return ds.labelGraph
+}
+
+// Communities returns each node's community (see Communities), worked out on first use
+func (ds *Dataset) Communities() map[string]int {
+	ds.commOnce.Do(func() {
+		ds.communities = Communities(ds.Graph)
+	})
+	return ds.communities
+}
+
+// RelationGraph returns the graph of the typed edges whose relation is allowed (see RelationGraph), built once per
+// relation set and kept, so repeated searches over the same relations don't rebuild it
+func (ds *Dataset) RelationGraph(allowed map[string]bool) *NodeGraph {
+	var relations []string
+	for r, ok := range allowed {
+		if ok {
+			relations = append(relations, r)
+		}
+	}
+	sort.Strings(relations)
+	key := strings.Join(relations, ",")
+
+	ds.relMu.Lock()
+	defer ds.relMu.Unlock()
+	if g, ok := ds.relGraphs[key]; ok {
+		return g
+	}
+	if ds.relGraphs == nil {
+		ds.relGraphs = make(map[string]*NodeGraph)
+	}
+	g := RelationGraph(ds.Graph, ds.Relations, allowed)
+	ds.relGraphs[key] = g
+	return g
+}
+
+// idFor maps a seed name from a side file to its node ID, names the graph doesn't know stay as they are
+func (ds *Dataset) idFor(name string) string {
+	if id, ok := ds.Resolve(name); ok {
+		return id
+	}
+	return name
+}
+
+// setNodes rekeys a nodes file by ID
+func (ds *Dataset) setNodes(nodes map[string]models.Person) {
+	ds.Nodes = make(map[string]models.Person, len(nodes))
+	for name, p := range nodes {
+		id := ds.idFor(name)
+		// Two seeds for one person, the one that isn't a redirect has the up to date metadata
+		if _, dup := ds.Nodes[id]; dup && p.Title != "" {
+			continue
+		}
+		ds.Nodes[id] = p
+	}
+}
+
+// setContexts rekeys a contexts file by ID
+func (ds *Dataset) setContexts(contexts map[string]map[string]models.LinkContext) {
+	ds.Contexts = make(map[string]map[string]models.LinkContext, len(contexts))
+	for from, links := range contexts {
+		byID := make(map[string]models.LinkContext, len(links))
+		for to, ctx := range links {
+			byID[ds.idFor(to)] = ctx
+		}
+		ds.Contexts[ds.idFor(from)] = byID
+	}
+}
+
+// setRelations rekeys a relations file by ID
+func (ds *Dataset) setRelations(relations models.TypedGraph) {
+	ds.Relations = make(models.TypedGraph, len(relations))
+	for from, edges := range relations {
+		byID := make([]models.TypedEdge, len(edges))
+		for i, e := range edges {
+			e.To = ds.idFor(e.To)
+			byID[i] = e
+		}
+		ds.Relations[ds.idFor(from)] = byID
+	}
+}
+
+// Person returns the metadata we have for a node, just the name if the nodes file doesn't know them
+func (ds *Dataset) Person(id string) models.Person {
+	p, ok := ds.Nodes[id]
+	if !ok {
+		p = models.Person{}
+	}
+	p.Name = ds.Label(id)
+	p.ID = id
+	p.Tags = ds.Tags[id]
+	p.Attrs = ds.Graph.Attrs(id)
+	return p
+}
+
+// HasTag reports whether a person was seeded with the given tag
+func (ds *Dataset) HasTag(name, tag string) bool {
+	for _, t := range ds.Tags[name] {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// PathTags returns each person's tags parallel to the path, nil when the graph has no tags
+func (ds *Dataset) PathTags(path []string) [][]string {
+	if len(ds.Tags) == 0 {
+		return nil
+	}
+	tags := make([][]string, len(path))
+	for i, name := range path {
+		tags[i] = ds.Tags[name]
+		if tags[i] == nil {
+			tags[i] = []string{} // [] instead of null keeps the array easy to index on the frontend
+		}
+	}
+	return tags
+}
+
+// Hops describes each edge of a path of IDs, with the link context when the contexts file has it and the edge's
+// type and weight when the graph file has them
+// Returns nil when there's neither so path_found stays as small as before
+func (ds *Dataset) Hops(path []string) []models.Hop {
+	if ds.Contexts == nil && !ds.Graph.HasEdgeAttrs() || len(path) < 2 {
+		return nil
+	}
+
+	hops := make([]models.Hop, 0, len(path)-1)
+	for i := 0; i+1 < len(path); i++ {
+		hop := models.Hop{From: ds.Label(path[i]), To: ds.Label(path[i+1])}
+		if ctx, ok := ds.Contexts[path[i]][path[i+1]]; ok {
+			hop.Context = &ctx
+			hop.Summary = summarize(hop.From, hop.To, ctx)
+		}
+		attrs := ds.Graph.EdgeAttrs(path[i], path[i+1])
+		hop.Relation = attrs.Type
+		hop.Weight = attrs.Weight
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// summarize renders a hop the way users read it, e.g. A's article mentions B in the 'Early life' section: "..."
+func summarize(from, to string, ctx models.LinkContext) string {
+	var where string
+	switch {
+	case ctx.Location == models.LocationInfobox:
+		where = "in its infobox"
+	case ctx.Location == models.LocationTemplate && ctx.Snippet == "":
+		where = "in a navigation template"
+	case ctx.Section == "":
+		where = "in the introduction"
+	default:
+		where = fmt.Sprintf("in the '%s' section", ctx.Section)
+	}
+
+	summary := fmt.Sprintf("%s's article mentions %s %s", from, to, where)
+	if ctx.Snippet != "" {
+		summary += fmt.Sprintf(": %q", ctx.Snippet)
+	}
+	return summary
+}
+
+// Store holds one graph per snapshot, language edition and variant
+type Store struct {
+	datasets map[string]*Dataset // Snapshot "/" dataset name -> dataset
+	loadedAt time.Time
+}
+
+// storeKey is how the store keys a dataset across snapshots, e.g. "2025/en.prose"
+func storeKey(snapshot, name string) string {
+	if snapshot == "" {
+		snapshot = DefaultSnapshot
+	}
+	return snapshot + "/" + name
+}
+
+// LoadStore loads graph.json plus every graph.<lang>.json and graph.<lang>.<variant>.json found in dir as the
+// DefaultSnapshot, then every subdirectory of dir/SnapshotsDir the same way as a snapshot of its own.
+// Variants share their language's nodes and contexts files. Every graph is validated with mode, a strict store
+// refuses to load a graph with problems, a lenient one repairs them with a warning (see Validate)
+func LoadStore(dir string, mode ValidationMode) (*Store, error) {
+	store := &Store{datasets: make(map[string]*Dataset), loadedAt: time.Now()}
+
+	if err := store.loadSnapshot(DefaultSnapshot, dir, mode); err != nil {
+		return nil, err
+	}
+	// graph.json is required, without it there's no default graph and no app
+	if _, ok := store.datasets[storeKey(DefaultSnapshot, datasetName(DefaultLang, ""))]; !ok {
+		return nil, fmt.Errorf("no %s found in %q", FileName(DefaultLang, ""), dir)
+	}
+
+	snapshots, err := snapshotDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(snapshots) {
+		if name == DefaultSnapshot {
+			return nil, fmt.Errorf("%s is reserved for the graphs in %q, rename %s", DefaultSnapshot, dir, snapshots[name])
+		}
+		if err := store.loadSnapshot(name, snapshots[name], mode); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// loadSnapshot loads the graphs in one directory, with their nodes, contexts and relations files
+func (s *Store) loadSnapshot(snapshot, dir string, mode ValidationMode) error {
+	files, err := graphFiles(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		log.Printf("No graphs in %q, skipping snapshot %s", dir, snapshot)
+		return nil
+	}
+	for _, gf := range files {
+		ds, err := loadDataset(gf.path, gf.lang, gf.variant, true, mode)
+		if err != nil {
+			return err
+		}
+		s.add(snapshot, ds)
+	}
+	return nil
+}
+
+// add puts a loaded dataset in the store under a snapshot
+func (s *Store) add(snapshot string, ds *Dataset) {
+	ds.Snapshot = snapshot
+	key := storeKey(snapshot, ds.Name)
+	s.datasets[key] = ds
+	if !ds.Meta.FetchedAt.IsZero() {
+		log.Printf("Loaded %s graph with %d nodes (fetched %s from %s)", key, ds.Graph.Len(), ds.Meta.FetchedAt.Format(time.RFC3339), ds.Meta.Source)
+	} else {
+		log.Printf("Loaded %s graph with %d nodes", key, ds.Graph.Len())
+	}
+}
+
+// LoadDataset loads one graph file in any format, plus the nodes, contexts and relations files next to it when
+// it's named like FileName says (a graph under any other name loads on its own)
+func LoadDataset(path string, mode ValidationMode) (*Dataset, error) {
+	lang, variant, ok := parseFileName(filepath.Base(path))
+	if !ok {
+		lang = DefaultLang
+	}
+	ds, err := loadDataset(path, lang, variant, ok, mode)
+	if err != nil {
+		return nil, err
+	}
+	ds.Snapshot = DefaultSnapshot
+	return ds, nil
+}
+
+// loadDataset loads and validates a graph file, with its side files when sideFiles is set
+// A binary file is searched where it's mapped, everything else is decoded
+func loadDataset(path, lang, variant string, sideFiles bool, mode ValidationMode) (*Dataset, error) {
+	binary, err := IsBinaryGraph(path)
+	if err != nil {
+		return nil, err
+	}
+	var ds *Dataset
+	if binary {
+		file, g, people, err := mapBinaryGraph(path, mode)
+		if err != nil {
+			return nil, err
+		}
+		ds = wrapDataset(path, lang, variant, file, g, people)
+	} else {
+		file, people, err := LoadAnyGraph(path)
+		if err != nil {
+			return nil, err
+		}
+		if ds, err = checkedDataset(path, lang, variant, file, people, mode); err != nil {
+			return nil, err
+		}
+	}
+	if !sideFiles {
+		return ds, nil
+	}
+	dir := filepath.Dir(path)
+	err = ds.loadSideFiles(func(name string) (string, []byte, error) {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		return path, data, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// checkedDataset validates a loaded graph file and wraps it, path is where it came from
+func checkedDataset(path, lang, variant string, file *models.GraphFile, people map[string]bool, mode ValidationMode) (*Dataset, error) {
+	if err := checkGraph(path, file.Graph, mode); err != nil {
+		return nil, err
+	}
+	return wrapDataset(path, lang, variant, file, NewNodeGraph(file), people), nil
+}
+
+// wrapDataset makes the dataset of a checked graph, path is where it came from
+func wrapDataset(path, lang, variant string, file *models.GraphFile, g *NodeGraph, people map[string]bool) *Dataset {
+	ds := newDataset(datasetName(lang, variant), lang, variant, file, g)
+	ds.People = people
+	ds.Path = path
+	return ds
+}
+
+// loadSideFiles adds the nodes, contexts and relations files of the dataset's language
+// read returns a file's path (for errors) and contents, all three files are optional so a missing one
+// (an error matching fs.ErrNotExist) is skipped
+func (ds *Dataset) loadSideFiles(read func(name string) (string, []byte, error)) error {
+	// The nodes file is optional, older builds only have the adjacency data
+	if path, data, err := read(NodesFileName(ds.Lang)); err == nil {
+		nodes, err := decodeSideFile[map[string]models.Person]("nodes", path, data)
+		if err != nil {
+			return err
+		}
+		ds.setNodes(nodes)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if path, data, err := read(ContextsFileName(ds.Lang)); err == nil {
+		contexts, err := decodeSideFile[map[string]map[string]models.LinkContext]("contexts", path, data)
+		if err != nil {
+			return err
+		}
+		ds.setContexts(contexts)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if path, data, err := read(RelationsFileName(ds.Lang)); err == nil {
+		relations, err := decodeSideFile[models.TypedGraph]("relations", path, data)
+		if err != nil {
+			return err
+		}
+		ds.setRelations(relations)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// snapshotDirs finds the snapshot subdirectories of dir/SnapshotsDir, name -> path
+func snapshotDirs(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, SnapshotsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			snapshots[e.Name()] = filepath.Join(dir, SnapshotsDir, e.Name())
+		}
+	}
+	return snapshots, nil
+}
+
+// graphFile is a graph on disk and the dataset it holds
+type graphFile struct {
+	lang, variant, path string
+	modTime             time.Time
+}
+
+// graphFiles finds the graph files in dir, one per dataset
+// A dataset can be there twice, as JSON and as the binary cmd/convert makes from it. The binary loads much
+// faster so it wins, unless the JSON is newer (refetched since the last convert) and the binary is stale.
+func graphFiles(dir string) ([]graphFile, error) {
+	var paths []string
+	for _, pattern := range []string{"graph*.json", "graph*.jsonl", "graph*.bin"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	byName := make(map[string]graphFile)
+	for _, path := range paths {
+		lang, variant, ok := parseFileName(filepath.Base(path))
+		if !ok {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		gf := graphFile{lang: lang, variant: variant, path: path, modTime: info.ModTime()}
+		name := datasetName(lang, variant)
+		prev, dup := byName[name]
+		if !dup {
+			byName[name] = gf
+			continue
+		}
+		bin, text := gf, prev
+		if filepath.Ext(prev.path) == ".bin" {
+			bin, text = prev, gf
+		}
+		if text.modTime.After(bin.modTime) {
+			log.Printf("%s is older than %s, loading the JSON (rerun cmd/convert)", bin.path, text.path)
+			byName[name] = text
+		} else {
+			byName[name] = bin
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := make([]graphFile, len(names))
+	for i, name := range names {
+		files[i] = byName[name]
+	}
+	return files, nil
+}
+
+// Get returns the dataset for a snapshot, language and variant
+// Empty snapshot means DefaultSnapshot, empty lang the default language and empty variant all links between people
+func (s *Store) Get(snapshot, lang, variant string) (*Dataset, error) {
+	ds, ok := s.datasets[storeKey(snapshot, datasetName(lang, variant))]
+	if !ok {
+		if snapshot != "" && !slices.Contains(s.Snapshots(), snapshot) {
+			return nil, fmt.Errorf("no graph snapshot %q (see /api/graphs)", snapshot)
+		}
+		where := ""
+		if snapshot != "" {
+			where = fmt.Sprintf(" in snapshot %q", snapshot)
+		}
+		if variant != "" {
+			return nil, fmt.Errorf("no %s graph loaded for language %q%s", variant, lang, where)
+		}
+		return nil, fmt.Errorf("no graph loaded for language %q%s", lang, where)
+	}
+	return ds, nil
+}
+
+// LoadedAt is when the store was loaded
+func (s *Store) LoadedAt() time.Time {
+	return s.loadedAt
+}
+
+// Names lists the loaded datasets ("latest/en", "latest/en.prose", "2025/en", ...) in sorted order
+func (s *Store) Names() []string {
+	return sortedKeys(s.datasets)
+}
+
+// Snapshots lists the loaded snapshots in sorted order, DefaultSnapshot included
+func (s *Store) Snapshots() []string {
+	seen := make(map[string]bool)
+	for _, ds := range s.datasets {
+		seen[ds.Snapshot] = true
+	}
+	return sortedKeys(seen)
+}
+
+// Datasets returns every loaded dataset, sorted by snapshot then name
+func (s *Store) Datasets() []*Dataset {
+	datasets := make([]*Dataset, 0, len(s.datasets))
+	for _, name := range s.Names() {
+		datasets = append(datasets, s.datasets[name])
+	}
+	return datasets
+}
+
+// sortedKeys returns a map's keys in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// writeFiles writes name -> content under dir, making directories as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadStoreSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"graph.json":    `{"version":2,"graph":{"Q1":["Q2"],"Q2":["Q1"]},"labels":{"Q1":"Marie Curie","Q2":"Pierre Curie"}}`,
+		"graph.de.json": `{"version":2,"graph":{"Q1":[]},"labels":{"Q1":"Marie Curie"}}`,
+		// Last year's build, before Pierre was fetched
+		"snapshots/2024/graph.json": `{"version":2,"graph":{"Q1":[]},"labels":{"Q1":"Marie Skłodowska-Curie"}}`,
+		"snapshots/empty/notes.txt": "nothing to serve here",
+	})
+
+	store, err := LoadStore(dir, Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := store.Names(); !reflect.DeepEqual(names, []string{"2024/en", "latest/de", "latest/en"}) {
+		t.Errorf("names = %v", names)
+	}
+	if snapshots := store.Snapshots(); !reflect.DeepEqual(snapshots, []string{"2024", DefaultSnapshot}) {
+		t.Errorf("snapshots = %v", snapshots)
+	}
+
+	// The same person by the label each snapshot knew them by
+	for snapshot, label := range map[string]string{"": "Marie Curie", DefaultSnapshot: "Marie Curie", "2024": "Marie Skłodowska-Curie"} {
+		ds, err := store.Get(snapshot, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, ok := ds.Resolve(label); !ok || id != "Q1" {
+			t.Errorf("snapshot %q resolves %q to %q", snapshot, label, id)
+		}
+	}
+
+	errs := []struct{ snapshot, lang, err string }{
+		{"1999", "", `no graph snapshot "1999"`},
+		{"2024", "de", `no graph loaded for language "de" in snapshot "2024"`},
+		{"", "fr", `no graph loaded for language "fr"`},
+	}
+	for _, tt := range errs {
+		if _, err := store.Get(tt.snapshot, tt.lang, ""); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("Get(%q, %q) err = %v, want %q", tt.snapshot, tt.lang, err, tt.err)
+		}
+	}
+}
+
+// A snapshot can't take the name the top level graphs are served under
+func TestLoadStoreReservedSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"graph.json": `{"version":2,"graph":{"Q1":[]}}`,
+		"snapshots/" + DefaultSnapshot + "/graph.json": `{"version":2,"graph":{"Q1":[]}}`,
+	})
+	if _, err := LoadStore(dir, Strict); err == nil || !strings.Contains(err.Error(), "is reserved") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// A graph built elsewhere can still have two nodes with one label, neither hides the other
+func TestDatasetSharedLabel(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"graph.json": `{"version":2,"graph":{"Q1":["Q2"],"Q2":["Q3"],"Q3":[]},"labels":{"Q1":"John Smith","Q2":"John Smith","Q3":"Pocahontas"}}`,
+	})
+	ds, err := LoadDataset(filepath.Join(dir, "graph.json"), Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{"John Smith (Q1)": {"John Smith (Q2)"}, "John Smith (Q2)": {"Pocahontas"}, "Pocahontas": {}}
+	if got := ds.LabelGraph(); !reflect.DeepEqual(got, models.Graph(want)) {
+		t.Errorf("label graph = %v, want %v", got, want)
+	}
+	for name, id := range map[string]string{"John Smith (Q2)": "Q2", "john smith (q1)": "Q1", "John Smith": "Q1", "Pocahontas": "Q3"} {
+		if got, ok := ds.Resolve(name); !ok || got != id {
+			t.Errorf("Resolve(%q) = %q, want %q", name, got, id)
+		}
+	}
+}
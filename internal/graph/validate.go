@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Problems Validate finds in an adjacency map
+const (
+	IssueDangling     = "dangling_edge"      // Links to a node that isn't a key, usually a seed whose page failed to fetch
+	IssueSelfLoop     = "self_loop"          // A node links to itself
+	IssueDuplicate    = "duplicate_neighbor" // The same neighbor twice in one list
+	IssueEmptyName    = "empty_name"         // An empty key or neighbor
+	IssueNilAdjacency = "nil_adjacency"      // A key whose neighbor list is null instead of []
+)
+
+// ValidationMode says what Validate does about the problems it finds
+type ValidationMode int
+
+const (
+	Lenient ValidationMode = iota // Repair the graph and report what was repaired
+	Strict                        // Leave the graph alone and fail
+)
+
+// GraphIssue is one problem in a graph
+type GraphIssue struct {
+	Kind     string `json:"kind"`
+	Node     string `json:"node"`
+	Neighbor string `json:"neighbor,omitempty"`
+}
+
+// String shows the node, plus the edge for issues about one
+func (i GraphIssue) String() string {
+	if i.Kind == IssueNilAdjacency || i.Kind == IssueEmptyName && i.Node == "" {
+		return fmt.Sprintf("%q", i.Node)
+	}
+	return fmt.Sprintf("%q -> %q", i.Node, i.Neighbor)
+}
+
+// ValidationReport lists what Validate found, in node order
+type ValidationReport struct {
+	Issues []GraphIssue   `json:"issues"`
+	ByKind map[string]int `json:"byKind"`
+}
+
+// Summary is a one line account of the issues, counts per kind plus a few examples
+func (r *ValidationReport) Summary() string {
+	if len(r.Issues) == 0 {
+		return "no problems"
+	}
+	kinds := make([]string, 0, len(r.ByKind))
+	for kind := range r.ByKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	counts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		counts[i] = fmt.Sprintf("%d %s", r.ByKind[kind], kind)
+	}
+
+	examples := make([]string, 0, 3)
+	for _, issue := range r.Issues[:min(len(r.Issues), 3)] {
+		examples = append(examples, issue.String())
+	}
+	return fmt.Sprintf("%s (e.g. %s)", strings.Join(counts, ", "), strings.Join(examples, ", "))
+}
+
+// Validate checks an adjacency map for dangling edges, self-loops, duplicate neighbors, empty names and null
+// neighbor lists. Strict mode returns an error when there's any, lenient mode repairs the graph in place:
+// the bad edges and empty keys are dropped and null lists become empty ones.
+// Dropping a dangling edge loses a link to a page the graph doesn't have, e.g. a seed whose fetch failed.
+func Validate(g models.Graph, mode ValidationMode) (*ValidationReport, error) {
+	report := &ValidationReport{Issues: []GraphIssue{}, ByKind: make(map[string]int)}
+	add := func(kind, node, neighbor string) {
+		report.Issues = append(report.Issues, GraphIssue{Kind: kind, Node: node, Neighbor: neighbor})
+		report.ByKind[kind]++
+	}
+
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		neighbors := g[name]
+		if name == "" {
+			add(IssueEmptyName, name, "")
+			if mode == Lenient {
+				delete(g, name)
+			}
+			continue
+		}
+		if neighbors == nil {
+			add(IssueNilAdjacency, name, "")
+		}
+
+		kept := make([]string, 0, len(neighbors))
+		clear(seen)
+		for _, to := range neighbors {
+			switch _, isKey := g[to]; {
+			case to == "":
+				add(IssueEmptyName, name, to)
+			case to == name:
+				add(IssueSelfLoop, name, to)
+			case seen[to]:
+				add(IssueDuplicate, name, to)
+			case !isKey:
+				add(IssueDangling, name, to)
+			default:
+				seen[to] = true
+				kept = append(kept, to)
+			}
+		}
+		if mode == Lenient {
+			g[name] = kept
+		}
+	}
+
+	if mode == Strict && len(report.Issues) > 0 {
+		return report, fmt.Errorf("graph has %d problems: %s", len(report.Issues), report.Summary())
+	}
+	return report, nil
+}
@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Rani-Codes/sixth_degree/models"
+)
+
+// Normalize sorts and deduplicates every neighbor list so the same data always produces the same file
+// Nil lists become empty ones so the JSON says [] instead of null
+func Normalize(g models.Graph) {
+	for name, neighbors := range g {
+		sorted := append([]string{}, neighbors...)
+		sort.Strings(sorted)
+
+		deduped := sorted[:0]
+		for i, n := range sorted {
+			if i > 0 && n == sorted[i-1] {
+				continue
+			}
+			deduped = append(deduped, n)
+		}
+		g[name] = deduped
+	}
+}
+
+// CountEdges returns the number of directed edges in the graph
+func CountEdges(g models.Graph) int {
+	edges := 0
+	for _, neighbors := range g {
+		edges += len(neighbors)
+	}
+	return edges
+}
+
+// WriteGraphFile normalizes the graph, fills in the counts and writes the envelope to path
+// A file still keyed by title gets titles as IDs, call AssignIDs first to key it by QID
+func WriteGraphFile(path string, file *models.GraphFile) error {
+	AssignIDs(file, nil)
+	Normalize(file.Graph)
+	file.Version = models.GraphFileVersion
+	file.Meta.Nodes = len(file.Graph)
+	file.Meta.Edges = CountEdges(file.Graph)
+
+	// encoding/json sorts map keys, so together with Normalize the output is deterministic
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph: %w", err)
+	}
+	return WriteFileAtomic(path, data)
+}
+
+// WriteFileAtomic writes to a temp file in the same directory then renames it over path
+// A crash halfway through leaves the old file in place instead of a truncated one
+func WriteFileAtomic(path string, data []byte) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFunc is WriteFileAtomic for output that's streamed instead of built up in memory
+func writeFileAtomicFunc(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Cleanup is a no-op once the rename went through
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriterSize(tmp, 1<<20)
+	if err := write(bw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+// AdminHandler serves the POST /api/admin/reload endpoint
+type AdminHandler struct {
+	live  *graph.Live
+	token string // Bearer token the endpoint wants, empty turns it off
+}
+
+func NewAdminHandler(live *graph.Live, token string) *AdminHandler {
+	return &AdminHandler{live: live, token: token}
+}
+
+// reloadResponse is what a reload answers with, the graphs served from now on
+type reloadResponse struct {
+	Graphs   []string  `json:"graphs"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// HandleReload loads the graphs from disk again and swaps them in, searches in flight finish on the old ones
+func (h *AdminHandler) HandleReload(w http.ResponseWriter, r *http.Request) {
+	// Without a token there's no admin API at all
+	if h.token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.live.Reload(); err != nil {
+		// The error names files and parse details, those stay in the server log
+		log.Printf("Admin reload failed: %v", err)
+		http.Error(w, "reload failed, still serving the previous graphs", http.StatusInternalServerError)
+		return
+	}
+	store := h.live.Store()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reloadResponse{Graphs: store.Names(), LoadedAt: store.LoadedAt()}); err != nil {
+		log.Printf("Error encoding reload response: %v", err)
+	}
+}
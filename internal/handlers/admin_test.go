@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+func TestHandleReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.json")
+	valid := `{"version":2,"graph":{"Q1":["Q2"],"Q2":[]},"labels":{"Q1":"Marie Curie","Q2":"Pierre Curie"}}`
+	if err := os.WriteFile(path, []byte(valid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	live, err := graph.NewLive(dir, graph.Strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string // What the server is started with
+		method string
+		auth   string
+		graph  string // Written before the request, empty keeps the last one
+		status int
+		json   bool
+	}{
+		{name: "off without a token", method: http.MethodPost, auth: "Bearer secret", status: http.StatusNotFound},
+		{name: "post only", token: "secret", method: http.MethodGet, auth: "Bearer secret", status: http.StatusMethodNotAllowed},
+		{name: "wrong token", token: "secret", method: http.MethodPost, auth: "Bearer guess", status: http.StatusUnauthorized},
+		{name: "broken graph", token: "secret", method: http.MethodPost, auth: "Bearer secret", graph: `{"version":2,"graph":{"Q1":["Q9"]}}`, status: http.StatusInternalServerError},
+		{name: "reloaded", token: "secret", method: http.MethodPost, auth: "Bearer secret", graph: valid, status: http.StatusOK, json: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.graph != "" {
+				if err := os.WriteFile(path, []byte(tt.graph), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			req := httptest.NewRequest(tt.method, "/api/admin/reload", nil)
+			req.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+			NewAdminHandler(live, tt.token).HandleReload(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if isJSON := rec.Header().Get("Content-Type") == "application/json"; isJSON != tt.json {
+				t.Errorf("content type = %q", rec.Header().Get("Content-Type"))
+			}
+			// Paths and parse errors stay in the server log
+			if strings.Contains(rec.Body.String(), dir) || strings.Contains(rec.Body.String(), "Q9") {
+				t.Errorf("body leaks the load error: %s", rec.Body)
+			}
+			if tt.json {
+				var res reloadResponse
+				if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || len(res.Graphs) != 1 {
+					t.Errorf("response = %+v, %v", res, err)
+				}
+			}
+		})
+	}
+}
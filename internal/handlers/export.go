@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
+)
+
+// ExportHandler serves the GET /api/export endpoint
+type ExportHandler struct {
+	live *graph.Live
+}
+
+func NewExportHandler(live *graph.Live) *ExportHandler {
+	return &ExportHandler{live: live}
+}
+
+// HandleExport streams a graph as GraphML, GEXF, DOT or CSV (?format=graphml|gexf|dot|nodes-csv|edges-csv)
+// graph, lang and variant pick the graph like they do on /api/graph
+func (h *ExportHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if !slices.Contains(graph.ExportFormats, format) {
+		http.Error(w, fmt.Sprintf("format must be one of %s", strings.Join(graph.ExportFormats, ", ")), http.StatusBadRequest)
+		return
+	}
+	ds, err := h.live.Store().Get(r.URL.Query().Get("graph"), r.URL.Query().Get("lang"), r.URL.Query().Get("variant"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	// Same limit as /api/graph, cmd/export handles the full article graph
+	if ds.Variant == graph.VariantFull {
+		http.Error(w, "the full article graph is too big to export here, use go run ./cmd/export", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", graph.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ds.Snapshot+"."+ds.Name+graph.ExportExt(format)))
+	// Headers are out once the body starts, so a failure halfway can only be logged
+	if err := graph.Export(w, ds, format); err != nil {
+		log.Printf("Error exporting %s as %s: %v", ds.Name, format, err)
+	}
+}
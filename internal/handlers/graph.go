@@ -2,22 +2,24 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
 	"github.com/Rani-Codes/sixth_degree/models"
 )
 
 // GraphHandler serves the GET /api/graph endpoint
 type GraphHandler struct {
-	graph models.Graph
+	live *graph.Live
 }
 
-func NewGraphHandler(graph models.Graph) *GraphHandler {
-	return &GraphHandler{graph: graph}
+func NewGraphHandler(live *graph.Live) *GraphHandler {
+	return &GraphHandler{live: live}
 }
 
-// HandleGetGraph returns the full adjacency map of the graph
+// HandleGetGraph returns the full adjacency map of the graph, or with ?nodes=1 the nodes and edges with their attributes
 func (h *GraphHandler) HandleGetGraph(w http.ResponseWriter, r *http.Request) {
 	// CORS + JSON headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -40,9 +42,49 @@ func (h *GraphHandler) HandleGetGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Stream the adjacency map as-is
-	if err := json.NewEncoder(w).Encode(h.graph); err != nil {
+	ds, err := h.live.Store().Get(r.URL.Query().Get("graph"), r.URL.Query().Get("lang"), r.URL.Query().Get("variant"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Millions of edges is no use to a browser
+	if ds.Variant == graph.VariantFull {
+		http.Error(w, "the full article graph is too big to send, search it over the websocket instead", http.StatusBadRequest)
+		return
+	}
+
+	// ?nodes=1 sends the nodes keyed by ID with their labels, attributes and typed edges
+	if r.URL.Query().Get("nodes") == "1" {
+		if err := json.NewEncoder(w).Encode(ds.Graph); err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Stream the adjacency map keyed by title, the same names the WebSocket search sends back
+	if err := json.NewEncoder(w).Encode(ds.LabelGraph()); err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 }
+
+// HandleListGraphs returns every loaded graph with its metadata, the values the graph, lang and variant parameters take
+func (h *GraphHandler) HandleListGraphs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	datasets := h.live.Store().Datasets()
+	graphs := make([]models.GraphInfo, len(datasets))
+	for i, ds := range datasets {
+		graphs[i] = ds.Info()
+	}
+	if err := json.NewEncoder(w).Encode(graphs); err != nil {
+		log.Printf("Error encoding graphs response: %v", err)
+	}
+}
@@ -4,29 +4,20 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"sort"
 	"strings"
 
+	"github.com/Rani-Codes/sixth_degree/internal/graph"
 	"github.com/Rani-Codes/sixth_degree/models"
 )
 
 // PeopleHandler handles the GET /api/people endpoint
 type PeopleHandler struct {
-	sortedNames []string
+	live *graph.Live
 }
 
-// NewPeopleHandler creates a new people handler and sorts the names from the graph
-func NewPeopleHandler(graph models.Graph) *PeopleHandler {
-	// Extract and sort names from the graph
-	var names []string
-	for name := range graph {
-		names = append(names, name)
-	}
-	sort.Strings(names)
-
-	return &PeopleHandler{
-		sortedNames: names,
-	}
+// NewPeopleHandler creates a new people handler, the sorted names come with each loaded graph (Dataset.PersonIndex)
+func NewPeopleHandler(live *graph.Live) *PeopleHandler {
+	return &PeopleHandler{live: live}
 }
 
 // HandleGetPeople handles GET /api/people requests
@@ -41,13 +32,22 @@ func (h *PeopleHandler) HandleGetPeople(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get search query parameter
+	// Pick the snapshot (empty means the latest), language edition (empty means English) and variant (empty means all links between people)
+	ds, err := h.live.Store().Get(r.URL.Query().Get("graph"), r.URL.Query().Get("lang"), r.URL.Query().Get("variant"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sortedNames := ds.PersonIndex()
+
+	// Get search query parameter, tag narrows it down to one curated group (nobel_laureate, olympian, ...)
 	query := r.URL.Query().Get("q")
+	tag := r.URL.Query().Get("tag")
 
 	var limit int
 	if query == "" {
-		// Return all people when browsing without a query
-		limit = len(h.sortedNames)
+		// Return all people (in the tag, if any) when browsing without a query
+		limit = len(sortedNames)
 	} else {
 		// Keep a tighter limit when filtering to keep responses snappy while typing
 		limit = 50
@@ -57,23 +57,26 @@ func (h *PeopleHandler) HandleGetPeople(w http.ResponseWriter, r *http.Request)
 	people := make([]models.Person, 0)
 	count := 0
 
+	// A QID or an old title finds the person too
+	exact, _ := ds.Resolve(query)
+
 	// Filter based on query using pre-sorted names
 	queryLower := strings.ToLower(query)
-	for _, name := range h.sortedNames {
+	for _, id := range sortedNames {
 		if count >= limit {
 			break
 		}
+		if tag != "" && !ds.HasTag(id, tag) {
+			continue
+		}
 
 		// Empty query shows all names, otherwise filter by query
+		// Person carries description, thumbnail and QID when the nodes file has them
 		if query == "" {
-			people = append(people, models.Person{
-				Name: name,
-			})
+			people = append(people, ds.Person(id))
 			count++
-		} else if strings.Contains(strings.ToLower(name), queryLower) {
-			people = append(people, models.Person{
-				Name: name,
-			})
+		} else if id == exact || strings.Contains(strings.ToLower(ds.Label(id)), queryLower) {
+			people = append(people, ds.Person(id))
 			count++
 		}
 	}
@@ -1,12 +1,92 @@
 package models
 
+import "time"
+
 // graph data structure models and type alias
 type Graph map[string][]string
 
+// GraphFileVersion is the current version of the graph.json envelope
+// Version 0 (no envelope) is the original bare adjacency map and version 1 the envelope keyed by article title,
+// LoadGraph still reads both and migrates them to node IDs. Version 3 added node and edge attributes.
+const GraphFileVersion = 3
+
+// EdgeAttrs is what an edge can carry besides its target, all optional
+type EdgeAttrs struct {
+	Weight     float64 `json:"weight,omitempty"`     // 0 means unweighted, searches count hops either way
+	Type       string  `json:"type,omitempty"`       // Kind of edge, e.g. a Wikidata relation (spouse, employer) or "reports_to" in an import. Empty is a plain link
+	Provenance string  `json:"provenance,omitempty"` // Where the edge came from when it isn't the graph's source, e.g. "wikidata"
+}
+
+// Edge is one outgoing edge of a node
+type Edge struct {
+	To string `json:"to"`
+	EdgeAttrs
+}
+
+// Node is one node with everything hanging off it, what /api/graph?nodes=1 sends
+type Node struct {
+	ID    string            `json:"id"`
+	Label string            `json:"label,omitempty"` // Name to show, empty when it's the ID
+	Attrs map[string]string `json:"attrs,omitempty"` // Anything else known about the node, e.g. the columns of an imported node file
+	Edges []Edge            `json:"edges"`
+}
+
+// GraphMeta records when and how a graph file was built
+type GraphMeta struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	SeedFile  string    `json:"seedFile"`
+	SeedHash  string    `json:"seedHash"` // sha256 of the seed file, tells you which seed list the graph came from
+	Source    string    `json:"source"`   // Wiki the links came from, e.g. en.wikipedia.org
+	Nodes     int       `json:"nodes"`
+	Edges     int       `json:"edges"`
+	Failed    int       `json:"failed"`           // Pages that couldn't be fetched and are missing from the graph
+	Reused    int       `json:"reused,omitempty"` // Pages carried over unchanged from the previous build (incremental fetch)
+	Shard     *Shard    `json:"shard,omitempty"`  // Set on the partial graphs of a sharded fetch, cleared by the merge
+}
+
+// Shard says which slice of the seed list a partial graph holds
+type Shard struct {
+	Index    int      `json:"index"`
+	Count    int      `json:"count"`
+	Assigned []string `json:"assigned"` // Seed names this shard was given, lets the merge spot missing pages
+}
+
+// GraphFile is the versioned envelope written to graph.json
+type GraphFile struct {
+	Version   int                             `json:"version"`
+	Meta      GraphMeta                       `json:"meta"`
+	Graph     Graph                           `json:"graph"`               // Keyed by node ID: the Wikidata QID, or the title for pages without one
+	Revisions map[string]int64                `json:"revisions,omitempty"` // ID -> revision the links were read from, lets the next fetch skip unchanged pages
+	Tags      Tags                            `json:"tags,omitempty"`      // ID -> curated groups from the seed list
+	Labels    map[string]string               `json:"labels,omitempty"`    // ID -> title to show, only for IDs that aren't titles themselves
+	Aliases   map[string]string               `json:"aliases,omitempty"`   // Former titles, redirects and other names -> ID
+	Attrs     map[string]map[string]string    `json:"attrs,omitempty"`     // ID -> node attributes (version 3)
+	EdgeAttrs map[string]map[string]EdgeAttrs `json:"edgeAttrs,omitempty"` // From -> to -> attributes, only for edges that have any (version 3)
+}
+
+// GraphInfo describes one loaded graph for /api/graphs
+type GraphInfo struct {
+	Graph   string    `json:"graph"`             // Snapshot, what the graph parameter takes
+	Lang    string    `json:"lang"`              // What the lang parameter takes
+	Variant string    `json:"variant,omitempty"` // What the variant parameter takes, empty for all links between people
+	Default bool      `json:"default"`           // Served when a request doesn't pick a graph, lang or variant
+	Nodes   int       `json:"nodes"`
+	Edges   int       `json:"edges"`
+	Version int       `json:"version"` // Graph file version it was loaded from
+	Meta    GraphMeta `json:"meta"`    // Empty for files from before the envelope
+}
+
+// Tags maps a person to the curated groups they were seeded from (nobel_laureate, olympian, supreme_court, ...)
+type Tags map[string][]string
+
 /*
 Datapipeline between BFS and Websocket connection
 Client sends:
 {"startNode": "Einstein", "endNode": "Newton"}
+startNode and endNode take a title, a former title or a QID like "Q937"
+or, to search another language edition's graph or only links written in article prose:
+{"startNode": "Einstein", "endNode": "Newton", "lang": "de", "variant": "prose"}
+and "graph": "2025" picks an older snapshot (listed by /api/graphs)
 
 Server streams back:
 {"type": "node_explored", "data": {"level": 1, "node": "Tesla"}}
@@ -17,6 +97,11 @@ Server streams back:
 type WSRequest struct {
 	StartNode string `json:"startNode"`
 	EndNode   string `json:"endNode"`
+	Graph     string `json:"graph,omitempty"`   // Snapshot to search (see /api/graphs), empty means the latest graphs
+	Lang      string `json:"lang,omitempty"`    // Language edition to search, empty means English
+	Variant   string `json:"variant,omitempty"` // "prose" skips infobox and navbox links, empty means all links between people
+	// Only follow typed Wikidata edges of these relations (or groups: "family", "colleague"), empty means hyperlinks
+	Relations []string `json:"relations,omitempty"`
 }
 
 type WSResponse struct {
@@ -35,8 +120,57 @@ type NodeExplored struct {
 }
 
 type PathFound struct {
-	Path   []string `json:"path"`
-	Length int      `json:"length"`
+	Path   []string   `json:"path"`
+	IDs    []string   `json:"ids,omitempty"` // Node IDs (Wikidata QIDs) parallel to Path, stable across article renames
+	Length int        `json:"length"`
+	Hops   []Hop      `json:"hops,omitempty"` // One per edge in Path, only sent when the graph has link context
+	Tags   [][]string `json:"tags,omitempty"` // Each person's domains, parallel to Path, only sent when the graph has tags
+}
+
+// Where in an article a link sits
+const (
+	LocationBody     = "body"     // Regular prose
+	LocationInfobox  = "infobox"  // Infobox parameter, e.g. spouse or doctoral advisor
+	LocationTemplate = "template" // Navbox or other template, the link isn't written in the article itself
+)
+
+// LinkContext says why article A links to B: which section, the sentence around it and where it sits
+type LinkContext struct {
+	Section  string `json:"section,omitempty"` // Empty for the lead section
+	Snippet  string `json:"snippet,omitempty"`
+	Location string `json:"location"`
+}
+
+// Relation types for typed edges
+const (
+	RelationSpouse   = "spouse"
+	RelationChild    = "child"
+	RelationParent   = "parent"
+	RelationSibling  = "sibling"
+	RelationEmployer = "employer"  // Same employer
+	RelationAward    = "award"     // Same award, in the same year when Wikidata says which year
+	RelationMemberOf = "member_of" // Same band, organization, academy, ...
+	RelationTeam     = "team"      // Same sports team
+)
+
+// Typed relationship edges from Wikidata, kept apart from the hyperlink graph
+type TypedGraph map[string][]TypedEdge
+
+type TypedEdge struct {
+	To       string `json:"to"`
+	Relation string `json:"relation"`           // spouse, child, parent, sibling, employer, award, member_of, team
+	Via      string `json:"via,omitempty"`      // For shared relations, the Wikidata item both people point at (the employer, the band, ...)
+	ViaLabel string `json:"viaLabel,omitempty"` // English label of Via, e.g. "Princeton University"
+}
+
+// Hop is one edge of a found path plus the context of the link behind it, if we have it
+type Hop struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Context  *LinkContext `json:"context,omitempty"`
+	Relation string       `json:"relation,omitempty"` // Set when the path came from typed Wikidata edges, or the edge has a type
+	Weight   float64      `json:"weight,omitempty"`   // The edge's weight, if the graph has weights
+	Summary  string       `json:"summary,omitempty"`  // Ready to show text, e.g. A's article mentions B in the 'Early life' section: "..."
 }
 
 // Use to create network vis by returning all nodes explored at certain level
@@ -46,5 +180,13 @@ type LevelExplored struct {
 }
 
 type Person struct {
-	Name string `json:"name"`
+	Name        string            `json:"name"`
+	ID          string            `json:"id,omitempty"`    // Node ID in the graph, the QID when there is one
+	Title       string            `json:"title,omitempty"` // Current article title, only when the seed name is an old title that now redirects
+	PageID      int               `json:"pageId,omitempty"`
+	Description string            `json:"description,omitempty"` // Wikipedia short description, tells apart people with similar names
+	Thumbnail   string            `json:"thumbnail,omitempty"`   // Lead image URL
+	QID         string            `json:"qid,omitempty"`         // Wikidata item ID
+	Tags        []string          `json:"tags,omitempty"`        // Curated groups from the seed list
+	Attrs       map[string]string `json:"attrs,omitempty"`       // The node's attributes, when the graph file has any
 }
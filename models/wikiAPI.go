@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 type WikiLinksResponse struct {
 	Continue struct {
 		Plcontinue string `json:"plcontinue"` //Pagination: Token to fetch next batch of links
@@ -12,10 +14,13 @@ type WikiLinksResponse struct {
 		} `json:"normalized"`
 		//Pages is a dynamic map. Key: pageid Value: a wiki page
 		Pages map[string]struct {
-			Pageid int    `json:"pageid"` //Wiki page ID (unique for each page)
-			Ns     int    `json:"ns"`     // Namespace ID (always 0 here, kept for completeness)
-			Title  string `json:"title"`
-			Links  []struct {
+			Pageid    int    `json:"pageid"` //Wiki page ID (unique for each page)
+			Ns        int    `json:"ns"`     // Namespace ID (always 0 here, kept for completeness)
+			Title     string `json:"title"`
+			Lastrevid int64  `json:"lastrevid"` // Latest revision ID (prop=info), tells us if the page changed since the last build
+			// Present (as an empty string) when the page doesn't exist, pointer so we can tell it apart from absent
+			Missing *string `json:"missing"`
+			Links   []struct {
 				Ns    int    `json:"ns"`
 				Title string `json:"title"`
 			} `json:"links"`
@@ -25,3 +30,156 @@ type WikiLinksResponse struct {
 		Links int `json:"links"` //Max links returned per page
 	} `json:"limits"`
 }
+
+// Response for prop=langlinks, used to map seed people across language editions
+type WikiLangLinksResponse struct {
+	Continue struct {
+		Llcontinue string `json:"llcontinue"` //Pagination: Token to fetch next batch of langlinks
+	} `json:"continue"`
+	Query struct {
+		Normalized []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"normalized"`
+		Redirects []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"redirects"`
+		Pages map[string]struct {
+			Title     string `json:"title"`
+			Langlinks []struct {
+				Lang  string `json:"lang"`
+				Title string `json:"*"` // Older json format puts the title under "*"
+			} `json:"langlinks"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// Response for prop=pageprops|pageimages|description, the per person metadata shown in search results
+type WikiPageInfoResponse struct {
+	// Continue holds whatever continuation params the API wants sent back (picontinue, excontinue, ...)
+	Continue map[string]string `json:"continue"`
+	Query    struct {
+		Normalized []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"normalized"`
+		Redirects []struct {
+			From string `json:"from"`
+			To   string `json:"to"` // Current title of a renamed article
+		} `json:"redirects"`
+		Pages map[string]struct {
+			Pageid      int     `json:"pageid"`
+			Title       string  `json:"title"`
+			Missing     *string `json:"missing"`
+			Description string  `json:"description"` // Short description, e.g. "German-born physicist (1879–1955)"
+			Thumbnail   struct {
+				Source string `json:"source"` // Lead image URL, scaled to pithumbsize
+			} `json:"thumbnail"`
+			Pageprops struct {
+				WikibaseItem string `json:"wikibase_item"` // Wikidata QID, e.g. Q937
+			} `json:"pageprops"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// Response for action=parse&prop=wikitext&formatversion=2, the raw article source used for link context
+type WikiParseResponse struct {
+	Parse struct {
+		Title    string `json:"title"`
+		Wikitext string `json:"wikitext"`
+	} `json:"parse"`
+	Error *struct {
+		Code string `json:"code"` // e.g. missingtitle
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// Response for prop=info, used to check revision IDs in bulk for incremental refetches
+type WikiInfoResponse struct {
+	Query struct {
+		Normalized []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"normalized"`
+		Pages map[string]struct {
+			Title     string  `json:"title"`
+			Missing   *string `json:"missing"`
+			Lastrevid int64   `json:"lastrevid"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// Response for prop=pageprops&ppprop=disambiguation&redirects=1, used to lint the seed list
+type WikiTitleCheckResponse struct {
+	Query struct {
+		Normalized []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"normalized"`
+		Redirects []struct {
+			From string `json:"from"`
+			To   string `json:"to"` // Article the redirect points at
+		} `json:"redirects"`
+		Pages map[string]struct {
+			Title     string  `json:"title"`
+			Missing   *string `json:"missing"`
+			Invalid   *string `json:"invalid"` // Title isn't allowed at all (bad characters, empty, ...)
+			Pageprops struct {
+				Disambiguation *string `json:"disambiguation"` // Present on disambiguation pages
+			} `json:"pageprops"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// Response for prop=categories, used to spot biographies when a page has no Wikidata item
+type WikiCategoriesResponse struct {
+	Continue map[string]string `json:"continue"`
+	Query    struct {
+		Normalized []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"normalized"`
+		Redirects []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"redirects"`
+		Pages map[string]struct {
+			Title      string  `json:"title"`
+			Missing    *string `json:"missing"`
+			Categories []struct {
+				Title string `json:"title"` // e.g. "Category:1879 births"
+			} `json:"categories"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// Response for Wikidata's action=wbgetentities&props=claims, the source of typed relationship edges
+type WikidataEntitiesResponse struct {
+	Entities map[string]struct {
+		ID      string                     `json:"id"`
+		Missing *string                    `json:"missing"`
+		Claims  map[string][]WikidataClaim `json:"claims"` // Property ID (P26, P40, ...) -> statements
+		Labels  map[string]struct {
+			Value string `json:"value"`
+		} `json:"labels"` // Language code -> label, with props=labels
+	} `json:"entities"`
+	Error *struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// WikidataClaim is one statement, e.g. "spouse: Q76346", with its qualifiers (point in time, ...)
+type WikidataClaim struct {
+	Mainsnak   WikidataSnak              `json:"mainsnak"`
+	Qualifiers map[string][]WikidataSnak `json:"qualifiers"`
+}
+
+type WikidataSnak struct {
+	Snaktype  string `json:"snaktype"` // "value", or "somevalue"/"novalue" which carry no datavalue
+	Datavalue struct {
+		Type  string          `json:"type"`  // "wikibase-entityid" for items, "time" for dates
+		Value json.RawMessage `json:"value"` // Shape depends on Type
+	} `json:"datavalue"`
+}